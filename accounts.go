@@ -0,0 +1,157 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/cli"
+	"aws-multitool/config"
+	"aws-multitool/core"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// sandboxLogin is the login of the account bound to profileName. Profiles
+// without an account get an empty login, so the provider uses its own.
+func sandboxLogin(profileName string) (core.WebsiteLogin, error) {
+	a, ok := config.Current.AccountFor(profileName)
+	if !ok {
+		return core.WebsiteLogin{}, nil
+	}
+	logins, err := queryLogins(config.Current.DB, a.Name)
+	if err != nil {
+		return core.WebsiteLogin{}, fmt.Errorf("reading the login of account %s: %w", a.Name, err)
+	}
+	if len(logins) == 0 {
+		return core.WebsiteLogin{}, fmt.Errorf("no login stored for account %s, add one with: account add --name %s", a.Name, a.Name)
+	}
+	// the newest login wins, as in retrieveCredentials
+	last := logins[len(logins)-1]
+	return core.WebsiteLogin{Url: last.URL, Username: last.Username, Password: last.Password}, nil
+}
+
+// refreshAccounts starts a sandbox for every account, carrying on past
+// failures
+func refreshAccounts() error {
+	if len(config.Current.Accounts) == 0 {
+		return errors.New("no accounts, add one with: account add")
+	}
+	var failed []string
+	for _, a := range config.Current.Accounts {
+		if cli.Human() {
+			fmt.Printf("Refreshing %s into profile %s\n", a.Name, a.Profile)
+		}
+		if err := refreshSandbox(a.Profile, a.Provider); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", a.Name, err)
+			failed = append(failed, a.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("refreshing %s failed", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// accountCommand manages the provider accounts: account list|add|remove
+func accountCommand(args []string) error {
+	action, args, err := subcommand(args, "list", "add", "remove")
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("account "+action, flag.ContinueOnError)
+	name := fs.String("name", "", "account name, e.g. alice")
+	provider := fs.String("provider", core.DefaultProvider, "sandbox provider of the account")
+	profileName := fs.String("profile", "", "profile its sandboxes are written to, defaults to sandbox-<name>")
+	url := fs.String("url", "", "login URL, defaults to the provider's")
+	username := fs.String("username", "", "login username")
+	password := fs.String("password", "", "login password")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if action == "list" {
+		return accountList()
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	file, err := config.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = cli.PromptGetInput(cli.PromptContent{Label: "Account name"})
+	}
+
+	if action == "remove" {
+		kept := file.Accounts[:0]
+		for _, a := range file.Accounts {
+			if a.Name != *name {
+				kept = append(kept, a)
+			}
+		}
+		if len(kept) == len(file.Accounts) {
+			return fmt.Errorf("account %s not found", *name)
+		}
+		file.Accounts = kept
+		return config.WriteFile(path, file)
+	}
+
+	if _, ok := file.Account(*name); ok {
+		return fmt.Errorf("account %s already exists", *name)
+	}
+	if _, err := core.Provider(*provider, core.WebsiteLogin{}, io.Discard); err != nil {
+		return err
+	}
+	if *profileName == "" {
+		*profileName = "sandbox-" + *name
+	}
+	if a, ok := file.AccountFor(*profileName); ok {
+		return fmt.Errorf("profile %s already belongs to account %s", *profileName, a.Name)
+	}
+
+	// keep the login in the credentials database, not the config file
+	if logins, _ := queryLogins(config.Current.DB, *name); len(logins) == 0 || *username != "" {
+		if *username == "" {
+			*username = cli.PromptGetInput(cli.PromptContent{Label: "Username"})
+			*password = cli.PromptSecret("Password")
+		}
+		if err := storeLogin(config.Current.DB, *name, *url, *username, *password); err != nil {
+			return err
+		}
+	}
+
+	file.Accounts = append(file.Accounts, config.Account{Name: *name, Provider: *provider, Profile: *profileName})
+	if err := config.WriteFile(path, file); err != nil {
+		return err
+	}
+	if cli.Human() {
+		fmt.Printf("Added account %s, refresh it with: sandbox refresh --account %s\n", *name, *name)
+	}
+	return nil
+}
+
+// accountList shows every account with the state of its profile
+func accountList() error {
+	store, err := awsStore()
+	if err != nil {
+		return err
+	}
+	views := []cli.Account{}
+	for _, a := range config.Current.Accounts {
+		view := cli.Account{Name: a.Name, Provider: a.Provider, Profile: a.Profile, State: awsconfig.NoExpiry.String()}
+		if logins, err := queryLogins(config.Current.DB, a.Name); err == nil && len(logins) > 0 {
+			view.Username = logins[len(logins)-1].Username
+		}
+		if p, err := store.Profile(a.Profile); err == nil {
+			view.State = awsconfig.StateOf(p.Expiration).String()
+			view.Expires = p.Expiry()
+		}
+		views = append(views, view)
+	}
+	return cli.Render(views)
+}
@@ -2,25 +2,28 @@ package acloud
 
 import (
 	"aws-multitool/cli"
+	"aws-multitool/config"
 	"aws-multitool/core"
+	"errors"
+	"io/fs"
 	"os"
-	"fmt"
 	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
 )
 
 func ACloudLogin(p ACloudProvider) (core.WebsiteLogin, error) {
-	login := ReadWebsiteLoginFromEnv(p)
-	fmt.Println("login : ", login)
-
-	return login, nil
+	return ReadWebsiteLoginFromEnv(p), nil
 }
 
 func ReadWebsiteLoginFromEnv(p ACloudProvider) core.WebsiteLogin {
-	ACloudEnv, err := cli.LoadEnvPath("./.env.acloud")
-	cli.PrintIfErr(err)
+	ACloudEnv, err := cli.LoadEnvPath(config.Current.ACloudEnv)
+	// the login may come from the environment itself
+	if !errors.Is(err, fs.ErrNotExist) {
+		cli.PrintIfErr(err)
+	}
 	p.ACloudEnv = ACloudEnv
 	return core.WebsiteLogin{
-		Url:      getEnv("URL", "https://learn.acloud.guru/cloud-playground/cloud-sandboxes"),
+		Url:      getEnv("URL", config.Current.SandboxURL),
 		Username: getEnv("USERNAME", ""),
 		Password: getEnv("PASSWORD", ""),
 	}
@@ -35,12 +38,22 @@ func getEnv(key, defaultValue string) string {
 }
 
 func ConnectBrowser(p ACloudProvider) (ACloudProvider, error) {
-	p.Connection.Browser = rod.New().MustConnect()
-	ACloudEnv, err := cli.LoadEnvPath("./.env.acloud")
-	cli.PrintIfErr(err)
-	p.ACloudEnv = ACloudEnv
+	p.Connection.Browser = launchBrowser(config.Current.Browser)
+	if p.ACloudEnv.Url == "" {
+		p.ACloudEnv.Url = config.Current.SandboxURL
+	}
 	Connection := core.Connect(p.Connection.Browser, p.ACloudEnv.Url)
-	cli.Success("Connection after: ", Connection)
+	cli.Debug(p.log, "Connection after: ", Connection)
 	p.Connection = Connection
 	return p, nil
-}
\ No newline at end of file
+}
+
+// launchBrowser starts the configured browser, or lets rod find or
+// download one when none is configured
+func launchBrowser(bin string) *rod.Browser {
+	if bin == "" {
+		return rod.New().MustConnect()
+	}
+	u := launcher.New().Bin(bin).MustLaunch()
+	return rod.New().ControlURL(u).MustConnect()
+}
@@ -0,0 +1,106 @@
+package acloud
+
+import (
+	"aws-multitool/config"
+	"aws-multitool/core"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func init() {
+	core.RegisterProvider(core.DefaultProvider, func(login core.WebsiteLogin, log io.Writer) core.SandboxProvider {
+		return &ACloudProvider{account: login, log: log}
+	})
+}
+
+// Login opens the playground in a browser and signs in with the
+// provider's account. The acloud env file is only read for a provider
+// without one.
+func (p *ACloudProvider) Login() error {
+	login := p.account
+	if login.Username == "" {
+		var err error
+		if login, err = ACloudLogin(*p); err != nil {
+			return err
+		}
+		if p.account.Url != "" {
+			login.Url = p.account.Url
+		}
+	}
+	if login.Url == "" {
+		login.Url = config.Current.SandboxURL
+	}
+	p.ACloudEnv.Url = login.Url
+	p.ACloudEnv.Username = login.Username
+	p.ACloudEnv.Password = login.Password
+
+	connected, err := ConnectBrowser(*p)
+	if err != nil {
+		return err
+	}
+	*p = connected
+
+	p.Connection, err = core.Login(login, p.Connection.Browser)
+	return err
+}
+
+// StartSandbox starts an AWS sandbox, or finds the one already running
+func (p *ACloudProvider) StartSandbox() error {
+	time.Sleep(1 * time.Second)
+	elems, err := Sandbox(p.Connection, p.ACloudEnv.Download_key)
+	if err != nil {
+		return err
+	}
+	p.elems = elems
+	return nil
+}
+
+// ExtractCredentials copies the keys and console login off the page
+func (p *ACloudProvider) ExtractCredentials() (core.SandboxCredentials, error) {
+	if len(p.elems) < 5 {
+		return core.SandboxCredentials{}, errors.New("no credentials found on the sandbox page")
+	}
+	creds, err := SimpleCopy(p.elems)
+	if err != nil {
+		return core.SandboxCredentials{}, err
+	}
+	p.SandboxCredential = creds
+	return core.SandboxCredentials{
+		LocalCreds: core.LocalCreds{
+			KeyID:      creds.KeyID,
+			AccessKey:  creds.AccessKey,
+			Region:     "us-east-1",
+			Expiration: creds.Expiration,
+		},
+		Console: core.WebsiteLogin{Url: creds.URL, Username: creds.User, Password: creds.Password},
+	}, nil
+}
+
+// Expiry is when the extracted credentials run out
+func (p *ACloudProvider) Expiry() time.Time {
+	return p.SandboxCredential.Expiration
+}
+
+// Stop deletes the running sandbox
+func (p *ACloudProvider) Stop() error {
+	if p.Connection.Page == nil {
+		if err := p.Login(); err != nil {
+			return err
+		}
+	}
+	button, err := p.Connection.Page.Timeout(30*time.Second).ElementR("button", "Delete Sandbox")
+	if err != nil {
+		return fmt.Errorf("no running sandbox found: %w", err)
+	}
+	if err := button.Click(proto.InputMouseButtonLeft, 1); err != nil {
+		return err
+	}
+	p.SandboxCredential = SandboxCredential{}
+	p.elems = rod.Elements{}
+	return nil
+}
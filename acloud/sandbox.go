@@ -4,27 +4,40 @@ import (
 	"aws-multitool/cli"
 	"aws-multitool/core"
 	"errors"
-	"fmt"
+	"io"
 	"github.com/go-rod/rod"
 	"time"
 )
 
+// ACloudProvider is the A Cloud Guru playground, registered as the
+// acloudguru sandbox provider
 type ACloudProvider struct {
 	cli.ACloudEnv
 	core.Connection
 	SandboxCredential
 	// *SQLiteRepository
+
+	// elems are the copy fields of the running sandbox
+	elems rod.Elements
+	// account overrides the login of the acloud env file
+	account core.WebsiteLogin
+	// log receives the progress of the scrape
+	log io.Writer
 }
 
 type SandboxCredential struct {
-	ID        int64
-	User      string
-	Password  string
-	URL       string
-	KeyID     string
-	AccessKey string
+	ID         int64
+	User       string
+	Password   string
+	URL        string
+	KeyID      string
+	AccessKey  string
+	Expiration time.Time
 }
 
+// SandboxDuration is how long an A Cloud Guru AWS sandbox lives
+var SandboxDuration = 4 * time.Hour
+
 func Sandbox(connect core.Connection, downloadKey string) (rod.Elements, error) {
 
 	elems := make(rod.Elements, 0)
@@ -56,6 +69,8 @@ func SimpleCopy(elems rod.Elements) (SandboxCredential, error) {
 		URL:       elems[2].MustText(),
 		KeyID:     elems[3].MustText(),
 		AccessKey: elems[4].MustText(),
+		// the sandbox clock starts when the credentials are handed out
+		Expiration: time.Now().Add(SandboxDuration),
 	}, nil
 }
 
@@ -75,22 +90,3 @@ func KeyVals(creds SandboxCredential) ([]string, []string) {
 
 	return keys, vals
 }
-
-func DisplayCreds(creds SandboxCredential) {
-	//if creds are empty, throw message and return
-	if creds.User == "" {
-		cli.Error("Warning: No Credentials Found")
-		return
-	}
-
-	fmt.Println("-----------------------------------------------------------------------------------")
-	fmt.Println("Sandbox Credentials: ")
-	fmt.Println("-----------------------------------------------------------------------------------")
-	fmt.Println("          " + cli.Cyan + "Username: " + cli.Yellow + creds.User + cli.Reset)
-	fmt.Println("          " + cli.Cyan + "Password: " + cli.Yellow + creds.Password + cli.Reset)
-	fmt.Println("          " + cli.Cyan + "URL: " + cli.Yellow + creds.URL + cli.Reset)
-	fmt.Println("          " + cli.Cyan + "KeyID: " + cli.Yellow + creds.KeyID + cli.Reset)
-	fmt.Println("          " + cli.Cyan + "AccessKey: " + cli.Yellow + creds.AccessKey + cli.Reset)
-	fmt.Println("-----------------------------------------------------------------------------------")
-}
-
@@ -0,0 +1,170 @@
+package awsconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of credential source a profile can end up using
+const (
+	SourceStatic           = "static"
+	SourceSession          = "session"
+	SourceAssumeRole       = "assume-role"
+	SourceProcess          = "credential_process"
+	SourceSSO              = "sso"
+	SourceWebIdentity      = "web-identity"
+	SourceCredentialSource = "credential_source"
+	SourceNone             = "none"
+)
+
+// CredentialSource is where a profile's credentials effectively come from,
+// found by walking role_arn/source_profile down to the base credentials
+type CredentialSource struct {
+	Kind string
+	// RoleArn is the role the profile assumes, if any
+	RoleArn string
+	// Chain lists the profiles walked, starting at the profile itself and
+	// ending at the one holding the base credentials
+	Chain []string
+	// Base is the kind of credentials at the end of the chain
+	Base string
+	// Detail is the process command, sso session or credential_source
+	Detail string
+	Err    error
+}
+
+func (c CredentialSource) String() string {
+	if c.Err != nil {
+		return "error: " + c.Err.Error()
+	}
+	switch c.Kind {
+	case SourceAssumeRole:
+		if len(c.Chain) > 1 {
+			return fmt.Sprintf("assumes %s via %s", c.RoleArn, strings.Join(c.Chain[1:], " → "))
+		}
+		if c.Detail != "" {
+			return fmt.Sprintf("assumes %s via %s", c.RoleArn, c.Detail)
+		}
+		return "assumes " + c.RoleArn
+	case SourceProcess:
+		return "credential_process: " + c.Detail
+	case SourceSSO:
+		return "sso: " + c.Detail
+	case SourceWebIdentity:
+		return "web identity: " + c.Detail
+	case SourceStatic:
+		return "static keys"
+	case SourceSession:
+		return "temporary keys"
+	}
+	return "no credentials"
+}
+
+// ResolveSources fills in the Source of every profile, and the account
+// ID of role profiles that do not record one from their role ARN
+func ResolveSources(profiles []AWSMaster) {
+	byName := map[string]*AWSMaster{}
+	for i := range profiles {
+		byName[profiles[i].Profile] = &profiles[i]
+	}
+	for i := range profiles {
+		profiles[i].Source = resolveSource(byName, profiles[i].Profile)
+		if profiles[i].AccountID == "" {
+			profiles[i].AccountID = arnAccount(profiles[i].Source.RoleArn)
+		}
+	}
+}
+
+// arnAccount returns the account field of an ARN,
+// arn:partition:service:region:account:resource
+func arnAccount(arn string) string {
+	if parts := strings.SplitN(arn, ":", 6); len(parts) == 6 && parts[0] == "arn" {
+		return parts[4]
+	}
+	return ""
+}
+
+func resolveSource(byName map[string]*AWSMaster, name string) CredentialSource {
+	source := CredentialSource{Chain: []string{name}}
+	visited := map[string]bool{}
+
+	for current := name; ; {
+		visited[current] = true
+		p := byName[current]
+		roleArn := p.OtherProps["role_arn"]
+
+		if roleArn == "" {
+			base := baseSource(p)
+			if current == name {
+				return base
+			}
+			source.Base = base.Kind
+			if base.Kind == SourceNone {
+				source.Err = fmt.Errorf("source profile %s has no credentials", current)
+			}
+			return source
+		}
+
+		if current == name {
+			source.Kind = SourceAssumeRole
+			source.RoleArn = roleArn
+		}
+
+		next := p.OtherProps["source_profile"]
+		switch {
+		case next == current:
+			// a profile may use its own static keys to assume its role
+			source.Base = SourceStatic
+			return source
+		case next != "":
+			if byName[next] == nil {
+				source.Err = fmt.Errorf("source_profile %s of %s does not exist", next, current)
+				return source
+			}
+			if visited[next] {
+				source.Err = fmt.Errorf("source_profile cycle: %s → %s", strings.Join(source.Chain, " → "), next)
+				return source
+			}
+			source.Chain = append(source.Chain, next)
+			current = next
+		case p.OtherProps["credential_source"] != "":
+			source.Base = SourceCredentialSource
+			source.Detail = p.OtherProps["credential_source"]
+			return source
+		case p.OtherProps["web_identity_token_file"] != "":
+			source.Base = SourceWebIdentity
+			source.Detail = p.OtherProps["web_identity_token_file"]
+			return source
+		default:
+			source.Err = fmt.Errorf("%s sets role_arn without source_profile or credential_source", current)
+			return source
+		}
+	}
+}
+
+// baseSource is the credential source of a profile that assumes no role
+func baseSource(p *AWSMaster) CredentialSource {
+	source := CredentialSource{Chain: []string{p.Profile}}
+	switch {
+	case p.OtherProps["web_identity_token_file"] != "":
+		source.Kind = SourceWebIdentity
+		source.Detail = p.OtherProps["web_identity_token_file"]
+	case p.OtherProps["sso_session"] != "":
+		source.Kind = SourceSSO
+		source.Detail = p.OtherProps["sso_session"]
+	case p.OtherProps["sso_start_url"] != "":
+		source.Kind = SourceSSO
+		source.Detail = p.OtherProps["sso_start_url"]
+	case p.AccessKey != "" && p.SessionToken != "":
+		source.Kind = SourceSession
+	case p.AccessKey != "":
+		source.Kind = SourceStatic
+	case p.OtherProps["credential_process"] != "":
+		source.Kind = SourceProcess
+		source.Detail = p.OtherProps["credential_process"]
+	default:
+		source.Kind = SourceNone
+	}
+	source.Base = source.Kind
+	return source
+}
@@ -0,0 +1,199 @@
+package awsconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CheckProfileName rejects names that cannot be written as a section
+// header and read back: empty ones, and ones with whitespace, brackets or
+// comment characters
+func CheckProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name is empty")
+	}
+	if strings.ContainsAny(name, " \t\r\n[]#;") {
+		return fmt.Errorf("invalid profile name %q: no spaces, brackets, # or ;", name)
+	}
+	return nil
+}
+
+// sections returns every section of the editor's file that defines profile
+func (e *editor) sections(profile string) []*Section {
+	var found []*Section
+	for _, s := range e.file.Sections() {
+		if name, ok := ProfileName(s.Name); ok && name == profile {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+// edit loads both files under the store lock, runs fn and saves whatever
+// fn changed
+func (s Store) edit(fn func(config, credentials *editor) error) ([]Change, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	configFile, err := loadOptional(s.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	credentialsFile, err := loadOptional(s.CredentialsPath)
+	if err != nil {
+		return nil, err
+	}
+	config := &editor{file: configFile}
+	credentials := &editor{file: credentialsFile}
+
+	if err := fn(config, credentials); err != nil {
+		return nil, err
+	}
+	if err := credentials.save(); err != nil {
+		return nil, err
+	}
+	if err := config.save(); err != nil {
+		return credentials.changes, err
+	}
+	return append(credentials.changes, config.changes...), nil
+}
+
+func exists(config, credentials *editor, profile string) bool {
+	return len(config.sections(profile)) > 0 || len(credentials.sections(profile)) > 0
+}
+
+// AddProfile creates a new profile. Credentials from u go to the
+// credentials file; region, output and any extra settings such as
+// role_arn go to the config file.
+func (s Store) AddProfile(u ProfileUpdate, settings map[string]string) ([]Change, error) {
+	if err := CheckProfileName(u.Profile); err != nil {
+		return nil, err
+	}
+	return s.edit(func(config, credentials *editor) error {
+		if exists(config, credentials, u.Profile) {
+			return fmt.Errorf("profile %s already exists", u.Profile)
+		}
+		if u.AccessKey != "" || u.SecretKey != "" {
+			section := credentials.section(u.Profile, u.Profile)
+			credentials.set(section, "aws_access_key_id", u.AccessKey)
+			credentials.set(section, "aws_secret_access_key", u.SecretKey)
+			if u.SessionToken != "" {
+				credentials.set(section, "aws_session_token", u.SessionToken)
+			}
+			if !u.Expiration.IsZero() {
+				credentials.set(section, ExpirationKey, formatExpiration(u.Expiration))
+			}
+		}
+
+		section := config.section(u.Profile, ConfigSectionName(u.Profile))
+		config.setDefault(section, "region", u.Region)
+		config.setDefault(section, "output", u.Output)
+		config.setAll(section, settings)
+		return nil
+	})
+}
+
+// CopyProfile clones a profile, in both files, under a new name
+func (s Store) CopyProfile(from, to string) ([]Change, error) {
+	if err := CheckProfileName(to); err != nil {
+		return nil, err
+	}
+	return s.edit(func(config, credentials *editor) error {
+		if !exists(config, credentials, from) {
+			return fmt.Errorf("profile %s not found", from)
+		}
+		if exists(config, credentials, to) {
+			return fmt.Errorf("profile %s already exists", to)
+		}
+		for _, src := range config.sections(from) {
+			config.copy(src, ConfigSectionName(to))
+		}
+		for _, src := range credentials.sections(from) {
+			credentials.copy(src, to)
+		}
+		return nil
+	})
+}
+
+// RenameProfile renames a profile in both files and repoints every
+// source_profile that referred to it
+func (s Store) RenameProfile(from, to string) ([]Change, error) {
+	if err := CheckProfileName(to); err != nil {
+		return nil, err
+	}
+	return s.edit(func(config, credentials *editor) error {
+		if !exists(config, credentials, from) {
+			return fmt.Errorf("profile %s not found", from)
+		}
+		if exists(config, credentials, to) {
+			return fmt.Errorf("profile %s already exists", to)
+		}
+		for _, section := range config.sections(from) {
+			config.rename(section, ConfigSectionName(to))
+		}
+		for _, section := range credentials.sections(from) {
+			credentials.rename(section, to)
+		}
+		for _, e := range []*editor{config, credentials} {
+			for _, section := range e.file.Sections() {
+				if v, _ := section.Get("source_profile"); v == from {
+					e.set(section, "source_profile", to)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteProfile removes a profile from both files. It refuses while
+// other profiles still name it as their source_profile.
+func (s Store) DeleteProfile(name string) ([]Change, error) {
+	return s.edit(func(config, credentials *editor) error {
+		if !exists(config, credentials, name) {
+			return fmt.Errorf("profile %s not found", name)
+		}
+		if users := sourceProfileUsers(name, config, credentials); len(users) > 0 {
+			return fmt.Errorf("profile %s is the source_profile of %s, change or delete those first", name, strings.Join(users, ", "))
+		}
+		for _, e := range []*editor{config, credentials} {
+			for _, section := range e.sections(name) {
+				if e.file.RemoveSection(section.Name) {
+					e.changes = append(e.changes, Change{File: e.file.Path, Section: section.Name, Action: Removed})
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (e *editor) copy(src *Section, header string) {
+	e.file.CopySection(src, header)
+	e.changes = append(e.changes, Change{File: e.file.Path, Section: header, Action: Created})
+}
+
+func (e *editor) rename(s *Section, header string) {
+	old := s.Name
+	s.Rename(header)
+	e.changes = append(e.changes, Change{File: e.file.Path, Section: old, Action: "renamed to [" + header + "]"})
+}
+
+// sourceProfileUsers lists the other profiles whose source_profile is name
+func sourceProfileUsers(name string, editors ...*editor) []string {
+	seen := map[string]bool{}
+	var users []string
+	for _, e := range editors {
+		for _, section := range e.file.Sections() {
+			profile, ok := ProfileName(section.Name)
+			if v, _ := section.Get("source_profile"); ok && v == name && profile != name && !seen[profile] {
+				seen[profile] = true
+				users = append(users, profile)
+			}
+		}
+	}
+	sort.Strings(users)
+	return users
+}
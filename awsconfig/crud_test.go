@@ -0,0 +1,56 @@
+package awsconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfileCRUD(t *testing.T) {
+	store := writeStore(t, `[profile base]
+region = us-east-1
+
+[profile admin]
+role_arn = arn:aws:iam::1:role/admin
+source_profile = base
+`, "[base]\naws_access_key_id = AKIABASE\naws_secret_access_key = secret\n")
+
+	if _, err := store.AddProfile(ProfileUpdate{Profile: "dev", Region: "eu-west-1"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddProfile(ProfileUpdate{Profile: "dev"}, nil); err == nil {
+		t.Error("adding an existing profile should fail")
+	}
+	for _, name := range []string{"", "my profile", "a]b", "x#y"} {
+		if _, err := store.AddProfile(ProfileUpdate{Profile: name}, nil); err == nil {
+			t.Errorf("profile name %q should be rejected", name)
+		}
+	}
+
+	if _, err := store.CopyProfile("base", "base2"); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := store.Profile("base2"); err != nil || p.AccessKey != "AKIABASE" {
+		t.Errorf("copy lost the keys: %+v %v", p, err)
+	}
+
+	if _, err := store.DeleteProfile("base"); err == nil || !strings.Contains(err.Error(), "admin") {
+		t.Errorf("deleting a source_profile should name its users, got %v", err)
+	}
+
+	if _, err := store.RenameProfile("base", "root"); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := store.Profile("admin"); p.OtherProps["source_profile"] != "root" {
+		t.Errorf("rename did not repoint source_profile: %v", p.OtherProps)
+	}
+
+	if _, err := store.DeleteProfile("admin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.DeleteProfile("root"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Profile("root"); err == nil {
+		t.Error("root should be gone")
+	}
+}
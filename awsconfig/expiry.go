@@ -0,0 +1,88 @@
+package awsconfig
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExpirationKey stores when temporary credentials run out. It is the key
+// saml2aws and similar tools already write, so the AWS CLI ignores it and
+// other tools can read it.
+const ExpirationKey = "x_security_token_expires"
+
+// WarnBefore is how long before expiry credentials count as expiring soon
+var WarnBefore = 15 * time.Minute
+
+// ExpiryState summarizes how close credentials are to expiring
+type ExpiryState int
+
+const (
+	NoExpiry ExpiryState = iota
+	Valid
+	ExpiringSoon
+	Expired
+)
+
+func (s ExpiryState) String() string {
+	switch s {
+	case Valid:
+		return "valid"
+	case ExpiringSoon:
+		return "expiring"
+	case Expired:
+		return "expired"
+	}
+	return "none"
+}
+
+// StateOf classifies an expiration time relative to now
+func StateOf(expiration time.Time) ExpiryState {
+	switch left := time.Until(expiration); {
+	case expiration.IsZero():
+		return NoExpiry
+	case left <= 0:
+		return Expired
+	case left <= WarnBefore:
+		return ExpiringSoon
+	}
+	return Valid
+}
+
+// DescribeExpiry renders an expiration as "expires in 1h5m" or
+// "expired 3m ago", or "" when none is recorded
+func DescribeExpiry(expiration time.Time) string {
+	if expiration.IsZero() {
+		return ""
+	}
+	left := time.Until(expiration).Round(time.Minute)
+	if left <= 0 {
+		return fmt.Sprintf("expired %s ago", shortDuration(-left))
+	}
+	return fmt.Sprintf("expires in %s", shortDuration(left))
+}
+
+// Expiry describes the profile's expiration for display
+func (m AWSMaster) Expiry() string {
+	return DescribeExpiry(m.Expiration)
+}
+
+// shortDuration drops the trailing "0s" that time.Duration prints
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if len(s) > 2 && s[len(s)-2:] == "0s" {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+func parseExpiration(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func formatExpiration(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
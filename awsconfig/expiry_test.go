@@ -0,0 +1,60 @@
+package awsconfig
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStateOf(t *testing.T) {
+	defer func(d time.Duration) { WarnBefore = d }(WarnBefore)
+	WarnBefore = 15 * time.Minute
+
+	tests := []struct {
+		expiration time.Time
+		want       ExpiryState
+	}{
+		{time.Time{}, NoExpiry},
+		{time.Now().Add(-time.Minute), Expired},
+		{time.Now().Add(10 * time.Minute), ExpiringSoon},
+		{time.Now().Add(time.Hour), Valid},
+	}
+	for _, test := range tests {
+		if got := StateOf(test.expiration); got != test.want {
+			t.Errorf("StateOf(%v) = %v, want %v", test.expiration, got, test.want)
+		}
+	}
+}
+
+func TestDescribeExpiry(t *testing.T) {
+	tests := []struct {
+		expiration time.Time
+		want       string
+	}{
+		{time.Time{}, ""},
+		{time.Now().Add(65*time.Minute + 10*time.Second), "expires in 1h5m"},
+		{time.Now().Add(-3*time.Minute - 10*time.Second), "expired 3m ago"},
+	}
+	for _, test := range tests {
+		if got := DescribeExpiry(test.expiration); got != test.want {
+			t.Errorf("DescribeExpiry(%v) = %q, want %q", test.expiration, got, test.want)
+		}
+	}
+}
+
+func TestParseExpiration(t *testing.T) {
+	want := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	if got := parseExpiration("2024-05-01T12:30:00Z"); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := parseExpiration("2024-05-01T14:30:00+02:00"); !got.Equal(want) {
+		t.Errorf("offsets should be honoured, got %v", got)
+	}
+	for _, bad := range []string{"", "tomorrow", "2024-05-01 12:30:00"} {
+		if got := parseExpiration(bad); !got.IsZero() {
+			t.Errorf("parseExpiration(%q) = %v, want zero", bad, got)
+		}
+	}
+	if got := parseExpiration(formatExpiration(want)); !got.Equal(want) {
+		t.Errorf("formatExpiration does not round-trip: %v", got)
+	}
+}
@@ -0,0 +1,437 @@
+package awsconfig
+
+import (
+	"os"
+	"strings"
+)
+
+// LineKind tells what a single physical line of an INI file holds
+type LineKind int
+
+const (
+	Blank LineKind = iota
+	Comment
+	Header
+	KeyValue
+	Continuation
+	Invalid
+)
+
+// Line is one physical line of an INI file. Raw is kept so that lines the
+// tool never touches are written back byte for byte.
+type Line struct {
+	Kind  LineKind
+	Num   int
+	Raw   string
+	Key   string
+	Value string
+	// Parent is the enclosing sub-section key (e.g. "s3") for nested keys
+	Parent string
+
+	indent  string
+	comment string
+	dirty   bool
+}
+
+func (l *Line) render() string {
+	if !l.dirty {
+		return l.Raw
+	}
+	return l.indent + l.Key + " = " + l.Value + l.comment
+}
+
+// Section is a [name] block and every line up to the next header
+type Section struct {
+	Name string
+
+	header *Line
+	lines  []*Line
+}
+
+// File is a parsed INI document that remembers its original layout, so
+// edits only change the lines they have to.
+type File struct {
+	Path string
+
+	head         []*Line
+	sections     []*Section
+	newline      string
+	finalNewline bool
+}
+
+// New returns an empty document that will be saved to path
+func New(path string) *File {
+	return &File{Path: path, newline: "\n", finalNewline: true}
+}
+
+// Load reads and parses the INI file at path
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := Parse(data)
+	f.Path = path
+	return f, nil
+}
+
+// Parse never fails: lines it does not understand are kept as Invalid so
+// they survive a round trip and can be reported by the linter.
+func Parse(data []byte) *File {
+	f := New("")
+	text := string(data)
+	if strings.Contains(text, "\r\n") {
+		f.newline = "\r\n"
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	if text == "" {
+		return f
+	}
+	raws := strings.Split(text, "\n")
+	f.finalNewline = raws[len(raws)-1] == ""
+	if f.finalNewline {
+		raws = raws[:len(raws)-1]
+	}
+
+	var cur *Section
+	var lastKey *Line
+	parent := ""
+	add := func(l *Line) {
+		if cur == nil {
+			f.head = append(f.head, l)
+		} else {
+			cur.lines = append(cur.lines, l)
+		}
+	}
+
+	for i, raw := range raws {
+		l := &Line{Num: i + 1, Raw: raw}
+		trimmed := strings.TrimSpace(raw)
+		indented := len(raw) > 0 && (raw[0] == ' ' || raw[0] == '\t')
+
+		switch {
+		case trimmed == "":
+			l.Kind = Blank
+			lastKey, parent = nil, ""
+			add(l)
+		case trimmed[0] == '#' || trimmed[0] == ';':
+			l.Kind = Comment
+			add(l)
+		case trimmed[0] == '[' && strings.Contains(trimmed, "]"):
+			l.Kind = Header
+			end := strings.Index(trimmed, "]")
+			cur = &Section{Name: strings.TrimSpace(trimmed[1:end]), header: l}
+			f.sections = append(f.sections, cur)
+			lastKey, parent = nil, ""
+		case indented && lastKey != nil && strings.Contains(trimmed, "=") && (parent != "" || lastKey.Value == ""):
+			parseKeyValue(l)
+			parent = lastKey.Key
+			l.Parent = parent
+			add(l)
+		case indented && lastKey != nil && parent == "":
+			l.Kind = Continuation
+			l.Key = lastKey.Key
+			l.Value = trimmed
+			lastKey.Value += "\n" + trimmed
+			add(l)
+		case strings.Contains(trimmed, "=") && cur != nil:
+			parseKeyValue(l)
+			lastKey, parent = l, ""
+			add(l)
+		default:
+			l.Kind = Invalid
+			add(l)
+		}
+	}
+	return f
+}
+
+// verbatimKeys hold commands, which like botocore are read whole: a # or ;
+// in them belongs to the command, not to a comment
+var verbatimKeys = map[string]bool{"credential_process": true}
+
+// parseKeyValue splits "key = value  # comment", keeping the indent and the
+// inline comment so a rewritten line still looks like the original.
+func parseKeyValue(l *Line) {
+	l.Kind = KeyValue
+	l.indent = l.Raw[:len(l.Raw)-len(strings.TrimLeft(l.Raw, " \t"))]
+	parts := strings.SplitN(strings.TrimSpace(l.Raw), "=", 2)
+	l.Key = strings.TrimSpace(parts[0])
+	value := parts[1]
+	if !verbatimKeys[l.Key] {
+		if i := inlineComment(value); i >= 0 {
+			l.comment = value[i:]
+			value = value[:i]
+		}
+	}
+	l.Value = strings.TrimSpace(value)
+	if l.comment != "" {
+		l.comment = " " + strings.TrimLeft(l.comment, " \t")
+	}
+}
+
+// inlineComment returns where a comment starts in value: at a # or ;
+// after whitespace and outside quotes. It returns -1 when there is none.
+func inlineComment(value string) int {
+	var quote rune
+	for i, r := range value {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case (r == '#' || r == ';') && i > 0 && (value[i-1] == ' ' || value[i-1] == '\t'):
+			return i - 1
+		}
+	}
+	return -1
+}
+
+// Bytes renders the document, reproducing the input exactly when nothing
+// was changed.
+func (f *File) Bytes() []byte {
+	var out []string
+	for _, l := range f.head {
+		out = append(out, l.render())
+	}
+	for _, s := range f.sections {
+		out = append(out, s.header.render())
+		for _, l := range s.lines {
+			out = append(out, l.render())
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	text := strings.Join(out, f.newline)
+	if f.finalNewline {
+		text += f.newline
+	}
+	return []byte(text)
+}
+
+// Sections returns every section in file order, duplicates included
+func (f *File) Sections() []*Section {
+	return f.sections
+}
+
+// Section returns the first section called name, or nil
+func (f *File) Section(name string) *Section {
+	for _, s := range f.sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// AddSection appends an empty [name] section, separated from the previous
+// content by a blank line.
+func (f *File) AddSection(name string) *Section {
+	if last := f.lastLine(); last != nil && last.Kind != Blank {
+		blank := &Line{Kind: Blank}
+		if n := len(f.sections); n > 0 {
+			f.sections[n-1].lines = append(f.sections[n-1].lines, blank)
+		} else {
+			f.head = append(f.head, blank)
+		}
+	}
+	s := &Section{Name: name, header: &Line{Kind: Header, Raw: "[" + name + "]"}}
+	f.sections = append(f.sections, s)
+	f.finalNewline = true
+	return s
+}
+
+// CopySection appends a copy of src, comments and layout included, under a
+// new header
+func (f *File) CopySection(src *Section, name string) *Section {
+	s := f.AddSection(name)
+	lines := src.lines
+	for len(lines) > 0 && lines[len(lines)-1].Kind == Blank {
+		lines = lines[:len(lines)-1]
+	}
+	for _, l := range lines {
+		c := *l
+		c.Num = 0
+		s.lines = append(s.lines, &c)
+	}
+	return s
+}
+
+// RemoveSection drops every section called name and reports whether any
+// were found.
+func (f *File) RemoveSection(name string) bool {
+	return f.removeSections(func(s *Section) bool { return s.Name == name })
+}
+
+func (f *File) removeSections(match func(*Section) bool) bool {
+	wasLast := len(f.sections) > 0 && match(f.sections[len(f.sections)-1])
+	kept := f.sections[:0]
+	for _, s := range f.sections {
+		if !match(s) {
+			kept = append(kept, s)
+		}
+	}
+	removed := len(kept) != len(f.sections)
+	f.sections = kept
+
+	// don't leave the separator of a removed last section dangling
+	if n := len(kept); wasLast && n > 0 {
+		last := kept[n-1]
+		for len(last.lines) > 0 && last.lines[len(last.lines)-1].Kind == Blank {
+			last.lines = last.lines[:len(last.lines)-1]
+		}
+	}
+	return removed
+}
+
+// Invalid returns the lines the parser could not make sense of, including
+// keys that appear before the first section header
+func (f *File) Invalid() []*Line {
+	var bad []*Line
+	for _, l := range f.head {
+		if l.Kind == Invalid {
+			bad = append(bad, l)
+		}
+	}
+	for _, s := range f.sections {
+		for _, l := range s.lines {
+			if l.Kind == Invalid {
+				bad = append(bad, l)
+			}
+		}
+	}
+	return bad
+}
+
+func (f *File) lastLine() *Line {
+	if n := len(f.sections); n > 0 {
+		s := f.sections[n-1]
+		if m := len(s.lines); m > 0 {
+			return s.lines[m-1]
+		}
+		return s.header
+	}
+	if n := len(f.head); n > 0 {
+		return f.head[n-1]
+	}
+	return nil
+}
+
+// Line returns the 1-based line number of the section header, 0 if new
+func (s *Section) Line() int {
+	return s.header.Num
+}
+
+// Rename changes the section header in place
+func (s *Section) Rename(name string) {
+	s.Name = name
+	s.header.Raw = "[" + name + "]"
+}
+
+// Get returns the value of a top-level key; the last occurrence wins
+func (s *Section) Get(key string) (string, bool) {
+	if l := s.find(key); l != nil {
+		return l.Value, true
+	}
+	return "", false
+}
+
+// Keys returns the top-level keys in file order
+func (s *Section) Keys() []string {
+	var keys []string
+	for _, l := range s.lines {
+		if l.Kind == KeyValue && l.Parent == "" {
+			keys = append(keys, l.Key)
+		}
+	}
+	return keys
+}
+
+// Entries returns every key line, nested sub-section keys included
+func (s *Section) Entries() []*Line {
+	var entries []*Line
+	for _, l := range s.lines {
+		if l.Kind == KeyValue {
+			entries = append(entries, l)
+		}
+	}
+	return entries
+}
+
+// Nested returns the keys of a sub-section such as "s3 =" blocks
+func (s *Section) Nested(parent string) map[string]string {
+	nested := map[string]string{}
+	for _, l := range s.lines {
+		if l.Kind == KeyValue && strings.EqualFold(l.Parent, parent) {
+			nested[l.Key] = l.Value
+		}
+	}
+	return nested
+}
+
+// Set updates key in place or appends it after the last key of the
+// section. It reports whether the document changed.
+func (s *Section) Set(key, value string) bool {
+	if l := s.find(key); l != nil {
+		if l.Value == value {
+			return false
+		}
+		s.dropChildren(l)
+		l.Value = value
+		l.dirty = true
+		return true
+	}
+
+	l := &Line{Kind: KeyValue, Key: key, Value: value, dirty: true}
+	at := len(s.lines)
+	for at > 0 && s.lines[at-1].Kind == Blank {
+		at--
+	}
+	s.lines = append(s.lines[:at], append([]*Line{l}, s.lines[at:]...)...)
+	return true
+}
+
+// Delete removes every occurrence of a top-level key along with its
+// continuation or nested lines, and reports whether anything was removed.
+func (s *Section) Delete(key string) bool {
+	removed := false
+	for l := s.find(key); l != nil; l = s.find(key) {
+		s.dropChildren(l)
+		for i, other := range s.lines {
+			if other == l {
+				s.lines = append(s.lines[:i], s.lines[i+1:]...)
+				break
+			}
+		}
+		removed = true
+	}
+	return removed
+}
+
+func (s *Section) find(key string) *Line {
+	var found *Line
+	for _, l := range s.lines {
+		if l.Kind == KeyValue && l.Parent == "" && strings.EqualFold(l.Key, key) {
+			found = l
+		}
+	}
+	return found
+}
+
+// dropChildren removes the continuation and nested lines that belong to l
+func (s *Section) dropChildren(l *Line) {
+	kept := s.lines[:0]
+	owned := false
+	for _, other := range s.lines {
+		child := other.Kind == Continuation || (other.Kind == KeyValue && other.Parent != "")
+		if owned && child {
+			continue
+		}
+		owned = other == l || (owned && other.Kind == Comment)
+		kept = append(kept, other)
+	}
+	s.lines = kept
+}
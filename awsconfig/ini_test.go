@@ -0,0 +1,128 @@
+package awsconfig
+
+import (
+	"testing"
+)
+
+const sample = `# managed by hand
+[default]
+region = us-east-1 # home
+output = json
+
+; work account
+[profile dev]
+  region = eu-west-1
+role_arn = arn:aws:iam::123456789012:role/dev
+source_profile = default
+s3 =
+  max_concurrent_requests = 20
+  multipart_threshold = 64MB
+unknown_key = keep me
+
+[profile broken]
+this line has no equals
+`
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"sample", sample},
+		{"crlf", "[default]\r\nregion = us-east-1\r\n"},
+		{"no final newline", "[default]\nregion = us-east-1"},
+		{"empty", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := string(Parse([]byte(test.input)).Bytes())
+			if got != test.input {
+				t.Errorf("round trip changed the file:\n%q\nwant\n%q", got, test.input)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	f := Parse([]byte(sample))
+
+	dev := f.Section("profile dev")
+	if dev == nil {
+		t.Fatal("expected [profile dev] section")
+	}
+	if region, _ := dev.Get("region"); region != "eu-west-1" {
+		t.Errorf("expected indented region eu-west-1, got %q", region)
+	}
+	if region, _ := f.Section("default").Get("region"); region != "us-east-1" {
+		t.Errorf("expected inline comment to be stripped, got %q", region)
+	}
+	if got := dev.Nested("s3")["max_concurrent_requests"]; got != "20" {
+		t.Errorf("expected nested s3 key 20, got %q", got)
+	}
+	if _, ok := dev.Get("max_concurrent_requests"); ok {
+		t.Error("nested key should not be visible at the top level")
+	}
+	if bad := f.Invalid(); len(bad) != 1 || bad[0].Num != 17 {
+		t.Errorf("expected one invalid line at 17, got %v", bad)
+	}
+}
+
+func TestEditsAreMinimal(t *testing.T) {
+	f := Parse([]byte(sample))
+	dev := f.Section("profile dev")
+	dev.Set("region", "eu-central-1")
+	dev.Set("output", "text")
+	f.Section("default").Set("region", "us-west-2")
+	f.RemoveSection("profile broken")
+	f.AddSection("sandbox").Set("aws_access_key_id", "AKIAEXAMPLE")
+
+	want := `# managed by hand
+[default]
+region = us-west-2 # home
+output = json
+
+; work account
+[profile dev]
+  region = eu-central-1
+role_arn = arn:aws:iam::123456789012:role/dev
+source_profile = default
+s3 =
+  max_concurrent_requests = 20
+  multipart_threshold = 64MB
+unknown_key = keep me
+output = text
+
+[sandbox]
+aws_access_key_id = AKIAEXAMPLE
+`
+	if got := string(f.Bytes()); got != want {
+		t.Errorf("unexpected edit result:\n%s\nwant\n%s", got, want)
+	}
+
+	dev.Delete("s3")
+	if len(dev.Nested("s3")) != 0 {
+		t.Error("deleting a sub-section key should drop its nested keys")
+	}
+}
+
+func TestInlineComments(t *testing.T) {
+	f := Parse([]byte(`[profile p]
+credential_process = sh -c "a ; b" # not a comment for commands
+mfa_serial = arn:aws:iam::1:mfa/x ; device
+role_session_name = "me ; you" # quoted
+color = red#no space
+`))
+	p := f.Section("profile p")
+	tests := map[string]string{
+		"credential_process": `sh -c "a ; b" # not a comment for commands`,
+		"mfa_serial":         "arn:aws:iam::1:mfa/x",
+		"role_session_name":  `"me ; you"`,
+		"color":              "red#no space",
+	}
+	for key, want := range tests {
+		if got, _ := p.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package awsconfig
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severities of lint problems
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is one finding of the linter, pointing at a file and line
+type Problem struct {
+	File     string
+	Line     int
+	Severity string
+	Message  string
+	// Fixable problems are repaired by Fix
+	Fixable bool
+}
+
+func (p Problem) String() string {
+	pos := p.File
+	if p.Line > 0 {
+		pos = fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	fix := ""
+	if p.Fixable {
+		fix = " (fixable)"
+	}
+	return fmt.Sprintf("%s: %s: %s%s", pos, p.Severity, p.Message, fix)
+}
+
+var accessKeyPattern = regexp.MustCompile(`^(AKIA|ASIA)[A-Z2-7]{16}$`)
+
+// knownKeys are the settings the AWS CLI and SDKs understand, plus the
+// ones this tool writes
+var knownKeys = map[string]bool{
+	"aws_access_key_id": true, "aws_secret_access_key": true, "aws_session_token": true,
+	"aws_security_token": true, "region": true, "output": true,
+	"role_arn": true, "source_profile": true, "credential_source": true, "external_id": true,
+	"mfa_serial": true, "role_session_name": true, "duration_seconds": true,
+	"web_identity_token_file": true, "credential_process": true,
+	"sso_session": true, "sso_start_url": true, "sso_region": true, "sso_account_id": true,
+	"sso_role_name": true, "sso_registration_scopes": true,
+	"ca_bundle": true, "cli_timestamp_format": true, "cli_follow_urlparam": true,
+	"cli_binary_format": true, "cli_pager": true, "cli_auto_prompt": true, "cli_history": true,
+	"parameter_validation": true, "max_attempts": true, "retry_mode": true,
+	"s3": true, "s3api": true, "sts_regional_endpoints": true, "use_fips_endpoint": true,
+	"use_dualstack_endpoint": true, "endpoint_url": true, "ignore_configure_endpoint_urls": true,
+	"services": true, "tcp_keepalive": true, "metadata_service_timeout": true,
+	"metadata_service_num_attempts": true, "defaults_mode": true, "api_versions": true,
+	"endpoint_discovery_enabled": true, "request_checksum_calculation": true,
+	"response_checksum_validation": true, "account_id_endpoint_mode": true, "aws_account_id": true,
+	ExpirationKey: true,
+}
+
+// Lint loads both AWS files and reports everything that is wrong or
+// suspicious about them, sorted by file and line
+func (s Store) Lint() ([]Problem, error) {
+	config, err := loadOptional(s.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := loadOptional(s.CredentialsPath)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := s.Profiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	add := func(file string, line int, severity string, fixable bool, format string, args ...interface{}) {
+		problems = append(problems, Problem{File: file, Line: line, Severity: severity, Fixable: fixable, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, f := range []*File{config, credentials} {
+		isConfig := f == config
+		for _, l := range f.Invalid() {
+			add(f.Path, l.Num, SeverityError, false, "cannot parse %q", strings.TrimSpace(l.Raw))
+		}
+
+		seen := map[string]int{}
+		for _, section := range f.Sections() {
+			name, ok := ProfileName(section.Name)
+			if !ok {
+				continue
+			}
+			if first, dup := seen[name]; dup {
+				add(f.Path, section.Line(), SeverityWarning, false, "profile %s is already defined at line %d", name, first)
+			} else {
+				seen[name] = section.Line()
+			}
+
+			if want := wantHeader(section.Name, name, isConfig); section.Name != want {
+				target := f.Section(want)
+				add(f.Path, section.Line(), SeverityWarning, target == nil || mergeable(section, target), "[%s] should be written [%s] in this file", section.Name, want)
+			}
+
+			for _, entry := range section.Entries() {
+				if entry.Parent != "" {
+					continue
+				}
+				if !knownKeys[entry.Key] && !isToolKey(entry.Key) {
+					add(f.Path, entry.Num, SeverityWarning, false, "unknown key %s in profile %s", entry.Key, name)
+				}
+				if entry.Key == "aws_access_key_id" && !accessKeyPattern.MatchString(entry.Value) {
+					add(f.Path, entry.Num, SeverityError, false, "malformed access key id %q in profile %s", entry.Value, name)
+				}
+			}
+		}
+
+		if !isConfig {
+			for name, line := range seen {
+				if !hasProfile(config, name) {
+					add(f.Path, line, SeverityWarning, true, "profile %s has no matching section in %s", name, config.Path)
+				}
+			}
+		}
+
+		if info, err := os.Stat(f.Path); err == nil && info.Mode().Perm()&0077 != 0 {
+			add(f.Path, 0, SeverityWarning, true, "permissions %04o let other users read it, expected 0600", info.Mode().Perm())
+		}
+	}
+
+	for _, p := range profiles {
+		if p.Source.Err != nil {
+			src := p.Sources["source_profile"]
+			if src.File == "" {
+				src = p.Sources["role_arn"]
+			}
+			add(src.File, src.Line, SeverityError, false, "profile %s: %v", p.Profile, p.Source.Err)
+		}
+		if p.Region == "" && p.Profile != "default" && !regionFromEnv() {
+			add(s.ConfigPath, configLine(config, p.Profile), SeverityWarning, false, "profile %s has no region", p.Profile)
+		}
+		for _, c := range p.Conflicts {
+			add(c.Credentials.File, c.Credentials.Line, SeverityWarning, false,
+				"%s of profile %s is also set at %s:%d", c.Key, p.Profile, c.Config.File, c.Config.Line)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// Fix repairs the fixable problems: section headers spelled for the other
+// file, credentials profiles missing from the config file and loose file
+// permissions.
+func (s Store) Fix() ([]Change, error) {
+	changes, err := s.edit(func(config, credentials *editor) error {
+		for _, e := range []*editor{config, credentials} {
+			// merging removes sections, so walk a copy
+			for _, section := range append([]*Section(nil), e.file.Sections()...) {
+				name, ok := ProfileName(section.Name)
+				if !ok {
+					continue
+				}
+				want := wantHeader(section.Name, name, e == config)
+				if section.Name == want {
+					continue
+				}
+				// a correctly spelled twin takes the keys it lacks; one
+				// that disagrees is left for the user to sort out
+				if target := e.file.Section(want); target != nil {
+					if mergeable(section, target) {
+						e.merge(section, target)
+					}
+					continue
+				}
+				e.rename(section, want)
+			}
+		}
+		for _, section := range credentials.file.Sections() {
+			if name, ok := ProfileName(section.Name); ok && !hasProfile(config.file, name) {
+				config.section(name, ConfigSectionName(name))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range []string{s.ConfigPath, s.CredentialsPath} {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+			if err := os.Chmod(path, 0600); err != nil {
+				return changes, err
+			}
+			changes = append(changes, Change{File: path, Action: fmt.Sprintf("permissions %04o changed to 0600", info.Mode().Perm())})
+		}
+	}
+	return changes, nil
+}
+
+// mergeable reports whether src can be folded into dst without losing
+// anything: it sets no key to a different value than dst does and has no
+// nested sub-section keys
+func mergeable(src, dst *Section) bool {
+	for _, entry := range src.Entries() {
+		if entry.Parent != "" {
+			return false
+		}
+		if v, ok := dst.Get(entry.Key); ok && v != entry.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// merge copies the keys dst lacks from src and drops src
+func (e *editor) merge(src, dst *Section) {
+	for _, key := range src.Keys() {
+		if _, ok := dst.Get(key); !ok {
+			value, _ := src.Get(key)
+			e.set(dst, key, value)
+		}
+	}
+	e.file.removeSections(func(s *Section) bool { return s == src })
+	e.changes = append(e.changes, Change{File: e.file.Path, Section: src.Name, Action: "merged into [" + dst.Name + "]"})
+}
+
+// wantHeader is the header a profile should have in its file; the config
+// file accepts both [default] and [profile default]
+func wantHeader(header, profile string, isConfig bool) string {
+	if isConfig && profile == "default" {
+		return header
+	}
+	if isConfig {
+		return ConfigSectionName(profile)
+	}
+	return profile
+}
+
+// isToolKey reports keys this tool stores alongside a profile
+func isToolKey(key string) bool {
+	return strings.HasPrefix(key, "multitool_")
+}
+
+func hasProfile(f *File, profile string) bool {
+	for _, section := range f.Sections() {
+		if name, ok := ProfileName(section.Name); ok && name == profile {
+			return true
+		}
+	}
+	return false
+}
+
+func regionFromEnv() bool {
+	return os.Getenv("AWS_REGION") != "" || os.Getenv("AWS_DEFAULT_REGION") != ""
+}
+
+func configLine(f *File, profile string) int {
+	for _, section := range f.Sections() {
+		if name, ok := ProfileName(section.Name); ok && name == profile {
+			return section.Line()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,117 @@
+package awsconfig
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	store := writeStore(t, `[default]
+region = us-east-1
+
+[dev]
+region = eu-west-1
+colour = blue
+
+[profile dev]
+region = eu-west-1
+`, `[ci]
+aws_access_key_id = nonsense
+`)
+	if err := os.Chmod(store.CredentialsPath, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := store.Lint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	text := strings.Join(got, "\n")
+	for _, want := range []string{
+		"config:4: warning: [dev] should be written [profile dev] in this file (fixable)",
+		"config:6: warning: unknown key colour in profile dev",
+		"config:8: warning: profile dev is already defined at line 4",
+		"credentials: warning: permissions 0644 let other users read it, expected 0600 (fixable)",
+		"credentials:1: warning: profile ci has no matching section in",
+		`credentials:2: error: malformed access key id "nonsense" in profile ci`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestFix(t *testing.T) {
+	store := writeStore(t, `[dev]
+output = json
+
+[profile dev]
+region = eu-west-1
+
+[prod]
+region = us-east-1
+
+[profile prod]
+region = us-west-2
+`, "[profile ci]\naws_access_key_id = AKIACI\n")
+
+	if _, err := store.Fix(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, _ := os.ReadFile(store.ConfigPath)
+	want := `[profile dev]
+region = eu-west-1
+output = json
+
+[prod]
+region = us-east-1
+
+[profile prod]
+region = us-west-2
+
+[profile ci]
+`
+	if string(config) != want {
+		t.Errorf("unexpected config file:\n%s", config)
+	}
+	credentials, _ := os.ReadFile(store.CredentialsPath)
+	if string(credentials) != "[ci]\naws_access_key_id = AKIACI\n" {
+		t.Errorf("unexpected credentials file:\n%s", credentials)
+	}
+
+	problems, _ := store.Lint()
+	for _, p := range problems {
+		if p.Fixable {
+			t.Errorf("still fixable after Fix: %s", p)
+		}
+	}
+}
+
+func TestFixPermissions(t *testing.T) {
+	store := writeStore(t, "", "")
+	if err := os.Chmod(store.ConfigPath, 0400); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(store.CredentialsPath, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := store.Fix()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].File != store.CredentialsPath {
+		t.Errorf("expected only the credentials file to change, got %v", changes)
+	}
+	if info, _ := os.Stat(store.ConfigPath); info.Mode().Perm() != 0400 {
+		t.Errorf("a 0400 file is private enough, got %04o", info.Mode().Perm())
+	}
+}
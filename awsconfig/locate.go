@@ -0,0 +1,47 @@
+package awsconfig
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Locate returns the config/credentials pair the tool should read and
+// write. An explicit awsDir wins, then AWS_CONFIG_FILE and
+// AWS_SHARED_CREDENTIALS_FILE, then ~/.aws under the user's home.
+func Locate(awsDir string) (Store, error) {
+	if awsDir != "" {
+		return Store{
+			ConfigPath:      filepath.Join(awsDir, "config"),
+			CredentialsPath: filepath.Join(awsDir, "credentials"),
+		}, nil
+	}
+	return LocateIn("")
+}
+
+// LocateIn is Locate without an explicit directory: AWS_CONFIG_FILE and
+// AWS_SHARED_CREDENTIALS_FILE win, and dir, or ~/.aws if it is empty,
+// holds whichever file they do not name.
+func LocateIn(dir string) (Store, error) {
+	store := Store{
+		ConfigPath:      os.Getenv("AWS_CONFIG_FILE"),
+		CredentialsPath: os.Getenv("AWS_SHARED_CREDENTIALS_FILE"),
+	}
+	if store.ConfigPath != "" && store.CredentialsPath != "" {
+		return store, nil
+	}
+
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return Store{}, err
+		}
+		dir = filepath.Join(homeDir, ".aws")
+	}
+	if store.ConfigPath == "" {
+		store.ConfigPath = filepath.Join(dir, "config")
+	}
+	if store.CredentialsPath == "" {
+		store.CredentialsPath = filepath.Join(dir, "credentials")
+	}
+	return store, nil
+}
@@ -0,0 +1,29 @@
+//go:build !unix
+
+package awsconfig
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// lockFile falls back to an exclusive lock file where flock is not
+// available, polling until the other run removes it
+func lockFile(path string) (func(), error) {
+	lock := path + ".lock"
+	for i := 0; ; i++ {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if i == 100 {
+			return nil, errors.New("timed out waiting for " + lock)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
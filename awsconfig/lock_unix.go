@@ -0,0 +1,25 @@
+//go:build unix
+
+package awsconfig
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive flock on path+".lock", waiting for any other
+// run of the tool to finish its write
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
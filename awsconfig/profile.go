@@ -0,0 +1,209 @@
+package awsconfig
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// AWSMaster is one logical profile, merged from the config and the
+// credentials file
+type AWSMaster struct {
+	Profile      string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	AccountID    string
+	OtherProps   map[string]string
+	// Sources records the file and line each key was read from
+	Sources map[string]Source
+	// Conflicts lists keys defined in both files
+	Conflicts []Conflict
+	// Source is the effective credential source after walking role chains
+	Source CredentialSource
+	// Expiration of temporary credentials, zero when none is recorded
+	Expiration time.Time
+}
+
+// ProviderKey names the sandbox provider that refreshes a profile
+const ProviderKey = "multitool_provider"
+
+// MFASourceKey names the IAM user profile an MFA session profile was
+// made from
+const MFASourceKey = "multitool_mfa_source"
+
+// Source is the position of a key in one of the AWS files
+type Source struct {
+	File string
+	Line int
+}
+
+// Conflict is a key set for the same profile in both files. The
+// credentials file wins, as it does for the AWS CLI.
+type Conflict struct {
+	Key              string
+	ConfigValue      string
+	CredentialsValue string
+	Config           Source
+	Credentials      Source
+}
+
+// Store points at a config/credentials file pair
+type Store struct {
+	ConfigPath      string
+	CredentialsPath string
+}
+
+// ProfileName maps a section header to the profile it defines. In the
+// config file profiles are written [profile x] (except [default]); the
+// credentials file uses bare [x]. Both spellings are accepted in either
+// file. Sections that are not profiles, such as [sso-session x], return
+// false.
+func ProfileName(section string) (string, bool) {
+	fields := strings.Fields(section)
+	switch {
+	case len(fields) == 1:
+		return fields[0], true
+	case len(fields) == 2 && fields[0] == "profile":
+		return fields[1], true
+	}
+	return "", false
+}
+
+// ConfigSectionName is the header a profile should have in the config file
+func ConfigSectionName(profile string) string {
+	if profile == "default" {
+		return profile
+	}
+	return "profile " + profile
+}
+
+// loadOptional parses path, treating a missing file as an empty one
+func loadOptional(path string) (*File, error) {
+	f, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(path), nil
+	}
+	return f, err
+}
+
+// Profiles returns one AWSMaster per logical profile, config file order
+// first, then profiles only present in the credentials file.
+func (s Store) Profiles() ([]AWSMaster, error) {
+	config, err := loadOptional(s.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := loadOptional(s.CredentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var order []string
+	byName := map[string]*AWSMaster{}
+	fromConfig := map[string]map[string]string{}
+
+	for _, f := range []*File{config, credentials} {
+		for _, section := range f.Sections() {
+			name, ok := ProfileName(section.Name)
+			if _, seen := byName[name]; ok && !seen {
+				byName[name] = &AWSMaster{Profile: name, Sources: map[string]Source{}}
+				fromConfig[name] = map[string]string{}
+				order = append(order, name)
+			}
+		}
+	}
+
+	merge := func(f *File, isCredentials bool) {
+		for _, section := range profileSectionsByPrecedence(f) {
+			name, _ := ProfileName(section.Name)
+			m := byName[name]
+			for _, entry := range section.Entries() {
+				if entry.Parent != "" {
+					continue
+				}
+				src := Source{File: f.Path, Line: entry.Num}
+				if isCredentials {
+					if prev, ok := fromConfig[name][entry.Key]; ok {
+						m.Conflicts = append(m.Conflicts, Conflict{
+							Key:              entry.Key,
+							ConfigValue:      prev,
+							CredentialsValue: entry.Value,
+							Config:           m.Sources[entry.Key],
+							Credentials:      src,
+						})
+					}
+				} else {
+					fromConfig[name][entry.Key] = entry.Value
+				}
+				m.set(entry.Key, entry.Value)
+				m.Sources[entry.Key] = src
+			}
+		}
+	}
+	merge(config, false)
+	merge(credentials, true)
+
+	profiles := make([]AWSMaster, 0, len(order))
+	for _, name := range order {
+		profiles = append(profiles, *byName[name])
+	}
+	ResolveSources(profiles)
+	return profiles, nil
+}
+
+// Profile returns a single merged profile by name
+func (s Store) Profile(name string) (AWSMaster, error) {
+	profiles, err := s.Profiles()
+	if err != nil {
+		return AWSMaster{}, err
+	}
+	for _, p := range profiles {
+		if p.Profile == name {
+			return p, nil
+		}
+	}
+	return AWSMaster{}, errors.New("profile not found: " + name)
+}
+
+// profileSectionsByPrecedence puts [x] before [profile x] so the explicit
+// spelling wins when a file has both.
+func profileSectionsByPrecedence(f *File) []*Section {
+	var bare, prefixed []*Section
+	for _, section := range f.Sections() {
+		if _, ok := ProfileName(section.Name); !ok {
+			continue
+		}
+		if strings.HasPrefix(section.Name, "profile ") {
+			prefixed = append(prefixed, section)
+		} else {
+			bare = append(bare, section)
+		}
+	}
+	return append(bare, prefixed...)
+}
+
+func (m *AWSMaster) set(key, value string) {
+	switch key {
+	case "aws_access_key_id":
+		m.AccessKey = value
+	case "aws_secret_access_key":
+		m.SecretKey = value
+	case "aws_session_token":
+		m.SessionToken = value
+	case ExpirationKey:
+		m.Expiration = parseExpiration(value)
+	case "region":
+		m.Region = value
+	case "aws_account_id", "sso_account_id":
+		m.AccountID = value
+		fallthrough
+	default:
+		if m.OtherProps == nil {
+			m.OtherProps = make(map[string]string)
+		}
+		m.OtherProps[key] = value
+	}
+}
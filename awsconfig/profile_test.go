@@ -0,0 +1,252 @@
+package awsconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeStore(t *testing.T, config, credentials string) Store {
+	dir := t.TempDir()
+	store := Store{
+		ConfigPath:      filepath.Join(dir, "config"),
+		CredentialsPath: filepath.Join(dir, "credentials"),
+	}
+	if err := os.WriteFile(store.ConfigPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.CredentialsPath, []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestProfiles(t *testing.T) {
+	store := writeStore(t, `[default]
+region = us-east-1
+
+[profile dev]
+region = eu-west-1
+aws_access_key_id = AKIACONFIG
+
+[sso-session corp]
+sso_region = us-east-1
+`, `[dev]
+aws_access_key_id = AKIACREDS
+aws_secret_access_key = secret
+
+[ci]
+aws_access_key_id = AKIACI
+`)
+
+	profiles, err := store.Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, p := range profiles {
+		names = append(names, p.Profile)
+	}
+	if len(names) != 3 || names[0] != "default" || names[1] != "dev" || names[2] != "ci" {
+		t.Fatalf("unexpected profiles %v", names)
+	}
+
+	dev := profiles[1]
+	if dev.AccessKey != "AKIACREDS" || dev.Region != "eu-west-1" {
+		t.Errorf("unexpected merge result %+v", dev)
+	}
+	if dev.Sources["region"].File != store.ConfigPath || dev.Sources["aws_secret_access_key"].File != store.CredentialsPath {
+		t.Errorf("unexpected sources %+v", dev.Sources)
+	}
+	if len(dev.Conflicts) != 1 || dev.Conflicts[0].Key != "aws_access_key_id" || dev.Conflicts[0].Config.Line != 6 {
+		t.Errorf("expected one access key conflict, got %+v", dev.Conflicts)
+	}
+}
+
+func TestUpsertProfile(t *testing.T) {
+	store := writeStore(t, "[default]\nregion = us-east-1\n", `# keep me
+[sandbox]
+aws_access_key_id = OLD
+aws_secret_access_key = OLD
+aws_session_token = STALE
+`)
+
+	changes, err := store.UpsertProfile(ProfileUpdate{
+		Profile:   "sandbox",
+		AccessKey: "AKIANEW",
+		SecretKey: "NEW",
+		Region:    "us-east-1",
+		Output:    "json",
+		Settings:  map[string]string{ProviderKey: "acloudguru"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Section+" "+c.Key+" "+c.Action)
+	}
+	want := []string{
+		"sandbox aws_access_key_id updated",
+		"sandbox aws_secret_access_key updated",
+		"sandbox aws_session_token removed",
+		"profile sandbox  created",
+		"profile sandbox region added",
+		"profile sandbox output added",
+		"profile sandbox multitool_provider added",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected changes %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	data, _ := os.ReadFile(store.CredentialsPath)
+	if string(data) != "# keep me\n[sandbox]\naws_access_key_id = AKIANEW\naws_secret_access_key = NEW\n" {
+		t.Errorf("unexpected credentials file:\n%s", data)
+	}
+
+	changes, err = store.UpsertProfile(ProfileUpdate{Profile: "fresh", AccessKey: "AKIAFRESH", SecretKey: "s"})
+	if err != nil || len(changes) != 3 || changes[0].Action != Created {
+		t.Errorf("expected a new section with two keys, got %v %v", changes, err)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	t.Setenv("HOME", "/home/someone")
+	t.Setenv("AWS_CONFIG_FILE", "/ci/aws-config")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "")
+
+	store, err := Locate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.ConfigPath != "/ci/aws-config" || store.CredentialsPath != "/home/someone/.aws/credentials" {
+		t.Errorf("unexpected env store %+v", store)
+	}
+
+	store, _ = Locate("/scratch")
+	if store.ConfigPath != "/scratch/config" || store.CredentialsPath != "/scratch/credentials" {
+		t.Errorf("--aws-dir should override the env, got %+v", store)
+	}
+
+	store, _ = LocateIn("/scratch")
+	if store.ConfigPath != "/ci/aws-config" || store.CredentialsPath != "/scratch/credentials" {
+		t.Errorf("the env should override a default dir, got %+v", store)
+	}
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	store := writeStore(t, "", "[sandbox]\naws_access_key_id = FIRST\n")
+
+	for _, key := range []string{"SECOND", "THIRD"} {
+		if _, err := store.UpsertProfile(ProfileUpdate{Profile: "sandbox", AccessKey: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(store.CredentialsPath)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected 0600 credentials file, got %v %v", info.Mode(), err)
+	}
+
+	backups, err := Backups(store.CredentialsPath)
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("expected two backups, got %v %v", backups, err)
+	}
+
+	// the oldest backup holds the original content
+	if err := Restore(store.CredentialsPath, backups[1]); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := store.Profile("sandbox")
+	if p.AccessKey != "FIRST" {
+		t.Errorf("expected restored key FIRST, got %q", p.AccessKey)
+	}
+	if backups, _ = Backups(store.CredentialsPath); len(backups) != 3 {
+		t.Errorf("restore should back up the file it replaces, got %d backups", len(backups))
+	}
+}
+
+func TestSaveThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "dotfiles-credentials")
+	if err := os.WriteFile(real, []byte("[sandbox]\naws_access_key_id = OLD\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := Store{ConfigPath: filepath.Join(dir, "config"), CredentialsPath: filepath.Join(dir, "credentials")}
+	if err := os.Symlink(real, store.CredentialsPath); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	if _, err := store.UpsertProfile(ProfileUpdate{Profile: "sandbox", AccessKey: "NEW"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(store.CredentialsPath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("credentials is no longer a symlink: %v %v", info, err)
+	}
+	p, _ := store.Profile("sandbox")
+	if p.AccessKey != "NEW" {
+		t.Errorf("expected the linked file to be updated, got %q", p.AccessKey)
+	}
+}
+
+func TestResolveSources(t *testing.T) {
+	store := writeStore(t, `[profile admin]
+role_arn = arn:aws:iam::111111111111:role/admin
+source_profile = dev
+
+[profile dev]
+role_arn = arn:aws:iam::111111111111:role/dev
+source_profile = dev-user
+
+[profile loop-a]
+role_arn = arn:aws:iam::111111111111:role/a
+source_profile = loop-b
+
+[profile loop-b]
+role_arn = arn:aws:iam::111111111111:role/b
+source_profile = loop-a
+
+[profile orphan]
+role_arn = arn:aws:iam::111111111111:role/orphan
+source_profile = nowhere
+
+[profile tool]
+credential_process = /usr/local/bin/get-creds
+`, `[dev-user]
+aws_access_key_id = AKIADEV
+aws_secret_access_key = secret
+`)
+
+	profiles, err := store.Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, p := range profiles {
+		got[p.Profile] = p.Source.String()
+		if p.Profile == "admin" && p.AccountID != "111111111111" {
+			t.Errorf("admin should take its account from the role ARN, got %q", p.AccountID)
+		}
+	}
+
+	want := map[string]string{
+		"admin":    "assumes arn:aws:iam::111111111111:role/admin via dev → dev-user",
+		"dev":      "assumes arn:aws:iam::111111111111:role/dev via dev-user",
+		"loop-a":   "error: source_profile cycle: loop-a → loop-b → loop-a",
+		"orphan":   "error: source_profile nowhere of orphan does not exist",
+		"tool":     "credential_process: /usr/local/bin/get-creds",
+		"dev-user": "static keys",
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s: got %q, want %q", name, got[name], w)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package awsconfig
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// RoleOptions are the settings a profile assumes its role with
+type RoleOptions struct {
+	RoleArn         string
+	SourceProfile   string
+	MFASerial       string
+	ExternalID      string
+	RoleSessionName string
+	// Duration is zero when the profile leaves it to STS
+	Duration time.Duration
+}
+
+// RoleOptionsOf reads role_arn, source_profile, mfa_serial, external_id,
+// role_session_name and duration_seconds from a profile
+func RoleOptionsOf(m AWSMaster) (RoleOptions, error) {
+	o := RoleOptions{
+		RoleArn:         m.OtherProps["role_arn"],
+		SourceProfile:   m.OtherProps["source_profile"],
+		MFASerial:       m.OtherProps["mfa_serial"],
+		ExternalID:      m.OtherProps["external_id"],
+		RoleSessionName: m.OtherProps["role_session_name"],
+	}
+	if value := m.OtherProps["duration_seconds"]; value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			src := m.Sources["duration_seconds"]
+			return o, fmt.Errorf("%s:%d: duration_seconds of profile %s is not a number of seconds: %q", src.File, src.Line, m.Profile, value)
+		}
+		o.Duration = time.Duration(seconds) * time.Second
+	}
+	return o, nil
+}
+
+// CacheKey is the file name the AWS CLI caches this role's sessions
+// under: the SHA-1 of its AssumeRole arguments as sorted JSON, leaving
+// out the session name and the MFA code
+func (o RoleOptions) CacheKey() string {
+	args := map[string]interface{}{"RoleArn": o.RoleArn}
+	if o.ExternalID != "" {
+		args["ExternalId"] = o.ExternalID
+	}
+	if o.MFASerial != "" {
+		args["SerialNumber"] = o.MFASerial
+	}
+	if o.Duration > 0 {
+		args["DurationSeconds"] = int(o.Duration.Seconds())
+	}
+	// encoding/json sorts map keys; Python's json.dumps also puts a space
+	// after each separator and leaves <, > and & alone
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(args)
+	sum := sha1.Sum(pythonSeparators(bytes.TrimSpace(b.Bytes())))
+	return hex.EncodeToString(sum[:])
+}
+
+// pythonSeparators turns compact JSON of flat string and number values
+// into the ", " and ": " spacing of json.dumps
+func pythonSeparators(data []byte) []byte {
+	out := make([]byte, 0, len(data)+16)
+	inString, escaped := false, false
+	for _, c := range data {
+		out = append(out, c)
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case !inString && (c == ',' || c == ':'):
+			out = append(out, ' ')
+		}
+	}
+	return out
+}
+
+// CachedSession is a session in the AWS CLI's assume-role cache
+type CachedSession struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// cacheFile is the JSON layout of the AWS CLI cache
+type cacheFile struct {
+	Credentials struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		SessionToken    string `json:"SessionToken"`
+		Expiration      string `json:"Expiration"`
+	} `json:"Credentials"`
+}
+
+// CLICacheDir is the AWS CLI's cache next to the config file,
+// ~/.aws/cli/cache by default
+func (s Store) CLICacheDir() string {
+	return filepath.Join(filepath.Dir(s.ConfigPath), "cli", "cache")
+}
+
+// LoadCachedSession reads a session from the AWS CLI cache
+func (s Store) LoadCachedSession(key string) (CachedSession, error) {
+	data, err := os.ReadFile(filepath.Join(s.CLICacheDir(), key+".json"))
+	if err != nil {
+		return CachedSession{}, err
+	}
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return CachedSession{}, err
+	}
+	c := f.Credentials
+	session := CachedSession{AccessKeyID: c.AccessKeyID, SecretAccessKey: c.SecretAccessKey, SessionToken: c.SessionToken}
+	// the AWS CLI writes 2006-01-02T15:04:05UTC
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05MST"} {
+		if t, err := time.Parse(layout, c.Expiration); err == nil {
+			session.Expiration = t
+			break
+		}
+	}
+	return session, nil
+}
+
+// SaveCachedSession writes a session to the AWS CLI cache, readable only
+// by the owner
+func (s Store) SaveCachedSession(key string, session CachedSession) error {
+	var f cacheFile
+	f.Credentials.AccessKeyID = session.AccessKeyID
+	f.Credentials.SecretAccessKey = session.SecretAccessKey
+	f.Credentials.SessionToken = session.SessionToken
+	f.Credentials.Expiration = formatExpiration(session.Expiration)
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.CLICacheDir(), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.CLICacheDir(), key+".json"), data, 0600)
+}
@@ -0,0 +1,44 @@
+package awsconfig
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoleCache(t *testing.T) {
+	store := writeStore(t, `[profile admin]
+role_arn = arn:aws:iam::123456789012:role/admin
+source_profile = dev
+mfa_serial = arn:aws:iam::123456789012:mfa/alice
+external_id = ext
+duration_seconds = 3600
+`, "")
+	m, err := store.Profile("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := RoleOptionsOf(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the keys the AWS CLI computes for the same arguments
+	if key := o.CacheKey(); key != "50ab58c11d61570c41ee24f767588f4adf2a2f08" {
+		t.Errorf("CacheKey() = %s", key)
+	}
+	if key := (RoleOptions{RoleArn: o.RoleArn}).CacheKey(); key != "85df843ebbf3964c64e26d66d796d302b1a7a5ff" {
+		t.Errorf("CacheKey() without options = %s", key)
+	}
+
+	session := CachedSession{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secret", SessionToken: "token", Expiration: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	if err := store.SaveCachedSession(o.CacheKey(), session); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.LoadCachedSession(o.CacheKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != session {
+		t.Errorf("got %+v, want %+v", got, session)
+	}
+}
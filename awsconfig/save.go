@@ -0,0 +1,199 @@
+package awsconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxBackups is how many timestamped copies of each AWS file are kept
+var MaxBackups = 5
+
+// BackupDirName is created next to the AWS files to hold the backups
+const BackupDirName = ".aws-multitool-backups"
+
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+// Backup is one saved copy of an AWS file
+type Backup struct {
+	Path string
+	Time time.Time
+}
+
+// Save writes the document back to its Path. The current file is backed
+// up first, then the new content goes to a temp file in the same
+// directory, is synced and renamed over the original, so a crash leaves
+// either the old or the new file and never a partial one. The result is
+// always 0600. Callers editing a Store should hold its lock.
+func (f *File) Save() error {
+	return writeFileAtomic(f.Path, f.Bytes())
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := backup(path); err != nil {
+		return err
+	}
+	// write through a symlink, as dotfile managers set them up, instead
+	// of replacing the link with a regular file
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir makes the rename durable; not every platform supports it, so
+// failures are ignored
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// backup copies path into the backup directory and prunes old copies. A
+// missing file has nothing to back up.
+func backup(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(filepath.Dir(path), BackupDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	name := filepath.Base(path) + "." + time.Now().UTC().Format(backupTimeFormat)
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		return err
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		return err
+	}
+	for i := MaxBackups; i < len(backups); i++ {
+		os.Remove(backups[i].Path)
+	}
+	return nil
+}
+
+// Backups lists the saved copies of path, newest first
+func Backups(path string) ([]Backup, error) {
+	dir := filepath.Join(filepath.Dir(path), BackupDirName)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(path) + "."
+	var backups []Backup
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimPrefix(entry.Name(), prefix))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: filepath.Join(dir, entry.Name()), Time: t})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	return backups, nil
+}
+
+// Restore rolls path back to the content of b. The file being replaced is
+// itself backed up, so a restore can be undone.
+func Restore(path string, b Backup) error {
+	if filepath.Dir(b.Path) != filepath.Join(filepath.Dir(path), BackupDirName) {
+		return fmt.Errorf("%s is not a backup of %s", b.Path, path)
+	}
+	data, err := os.ReadFile(b.Path)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeFileAtomic(path, data)
+}
+
+// lock takes the advisory locks of both files, always in the same order
+// so two runs cannot deadlock each other
+func (s Store) lock() (func(), error) {
+	paths := []string{s.ConfigPath, s.CredentialsPath}
+	sort.Strings(paths)
+
+	var unlocks []func()
+	release := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, path := range paths {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			release()
+			return nil, err
+		}
+		unlock, err := lockFile(path)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlock)
+	}
+	return release, nil
+}
+
+// LockFile takes the lock the store uses for its own files on path, for
+// other files two runs of the tool must not rewrite at once
+func LockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return lockFile(path)
+}
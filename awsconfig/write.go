@@ -0,0 +1,156 @@
+package awsconfig
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ProfileUpdate is the set of values to write for one profile. Empty
+// credential fields are left alone, except SessionToken and Expiration:
+// credentials without them must not keep old ones around. Region and
+// Output are defaults, only written when the config profile has none.
+// Settings are written to the config profile as given.
+type ProfileUpdate struct {
+	Profile      string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Expiration   time.Time
+	Region       string
+	Output       string
+	Settings     map[string]string
+}
+
+// Change describes a single edit made to one of the AWS files
+type Change struct {
+	File    string
+	Section string
+	Key     string
+	Action  string
+}
+
+const (
+	Added   = "added"
+	Updated = "updated"
+	Removed = "removed"
+	Created = "created"
+)
+
+func (c Change) String() string {
+	if c.Section == "" {
+		return fmt.Sprintf("%s: %s", c.File, c.Action)
+	}
+	if c.Key == "" {
+		return fmt.Sprintf("%s: [%s] %s", c.File, c.Section, c.Action)
+	}
+	return fmt.Sprintf("%s: [%s] %s %s", c.File, c.Section, c.Key, c.Action)
+}
+
+// staleKeys are credential keys that belong to an older set of
+// credentials and are removed whenever new keys are written
+var staleKeys = []string{"aws_security_token"}
+
+// editor collects the changes made to one file
+type editor struct {
+	file    *File
+	changes []Change
+}
+
+// section finds the section for profile in either spelling, creating it
+// with the preferred header when it is missing
+func (e *editor) section(profile, header string) *Section {
+	if found := e.sections(profile); len(found) > 0 {
+		return found[0]
+	}
+	e.changes = append(e.changes, Change{File: e.file.Path, Section: header, Action: Created})
+	return e.file.AddSection(header)
+}
+
+func (e *editor) set(s *Section, key, value string) {
+	_, existed := s.Get(key)
+	if !s.Set(key, value) {
+		return
+	}
+	action := Updated
+	if !existed {
+		action = Added
+	}
+	e.changes = append(e.changes, Change{File: e.file.Path, Section: s.Name, Key: key, Action: action})
+}
+
+func (e *editor) setDefault(s *Section, key, value string) {
+	if _, ok := s.Get(key); ok || value == "" {
+		return
+	}
+	e.set(s, key, value)
+}
+
+// setAll sets every non-empty value of settings, in key order
+func (e *editor) setAll(s *Section, settings map[string]string) {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if settings[key] != "" {
+			e.set(s, key, settings[key])
+		}
+	}
+}
+
+func (e *editor) remove(s *Section, key string) {
+	if s.Delete(key) {
+		e.changes = append(e.changes, Change{File: e.file.Path, Section: s.Name, Key: key, Action: Removed})
+	}
+}
+
+// save writes the file back only if something changed
+func (e *editor) save() error {
+	if len(e.changes) == 0 {
+		return nil
+	}
+	return e.file.Save()
+}
+
+// UpsertProfile writes u into the credentials file and the matching
+// config section, creating sections and keys as needed, and reports
+// exactly what changed.
+func (s Store) UpsertProfile(u ProfileUpdate) ([]Change, error) {
+	if err := CheckProfileName(u.Profile); err != nil {
+		return nil, err
+	}
+	return s.edit(func(config, credentials *editor) error {
+		if u.AccessKey != "" || u.SecretKey != "" || u.SessionToken != "" {
+			section := credentials.section(u.Profile, u.Profile)
+			if u.AccessKey != "" {
+				credentials.set(section, "aws_access_key_id", u.AccessKey)
+			}
+			if u.SecretKey != "" {
+				credentials.set(section, "aws_secret_access_key", u.SecretKey)
+			}
+			if u.SessionToken != "" {
+				credentials.set(section, "aws_session_token", u.SessionToken)
+			} else {
+				credentials.remove(section, "aws_session_token")
+			}
+			if !u.Expiration.IsZero() {
+				credentials.set(section, ExpirationKey, formatExpiration(u.Expiration))
+			} else {
+				credentials.remove(section, ExpirationKey)
+			}
+			for _, key := range staleKeys {
+				credentials.remove(section, key)
+			}
+		}
+
+		if u.Region != "" || u.Output != "" || len(u.Settings) > 0 {
+			section := config.section(u.Profile, ConfigSectionName(u.Profile))
+			config.setDefault(section, "region", u.Region)
+			config.setDefault(section, "output", u.Output)
+			config.setAll(section, u.Settings)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"aws-multitool/awsconfig"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// DashboardAction is a single-key action on the selected profile
+type DashboardAction struct {
+	Key   byte
+	Label string
+	Run   func(profile string) error
+}
+
+// escape sequences for the alternate screen the dashboard draws on
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen = "\x1b[H\x1b[2J"
+	reverse     = "\x1b[7m"
+)
+
+type dashboard struct {
+	profiles []Profile
+	actions  []DashboardAction
+	cursor   int
+	status   string
+}
+
+// Dashboard shows the profiles full-screen with live expiry countdowns
+// until q is pressed. Actions run on the normal screen so they can print
+// and prompt; load is called again after each one. Enter runs the first
+// action.
+func Dashboard(load func() ([]Profile, error), actions []DashboardAction) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("the dashboard needs a terminal")
+	}
+	profiles, err := load()
+	if err != nil {
+		return err
+	}
+	d := dashboard{profiles: profiles, actions: actions}
+
+	for {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		fmt.Print(enterScreen)
+		action, quit := d.run(fd)
+		fmt.Print(leaveScreen)
+		term.Restore(fd, state)
+		if quit {
+			return nil
+		}
+
+		name := d.profiles[d.cursor].Name
+		if err := action.Run(name); err != nil {
+			d.status = Red + action.Label + " " + name + ": " + err.Error() + Reset
+			fmt.Println(d.status)
+		} else {
+			d.status = Green + action.Label + " " + name + ": done" + Reset
+		}
+		fmt.Print("\nPress Enter to return to the dashboard")
+		fmt.Scanln()
+
+		if profiles, err := load(); err != nil {
+			d.status = Red + err.Error() + Reset
+		} else {
+			// the action may have removed profiles from under the cursor
+			d.profiles = profiles
+			d.clamp()
+		}
+	}
+}
+
+// run draws and reads keys until an action is chosen or the user quits
+func (d *dashboard) run(fd int) (DashboardAction, bool) {
+	buf := make([]byte, 8)
+	for {
+		d.draw(fd)
+		if !waitInput(fd, time.Second) {
+			continue
+		}
+		n, err := os.Stdin.Read(buf)
+		if err != nil || n == 0 {
+			return DashboardAction{}, true
+		}
+		switch key := string(buf[:n]); key {
+		case "q", "\x1b", "\x03":
+			return DashboardAction{}, true
+		case "\x1b[A", "\x1bOA", "k":
+			d.cursor--
+		case "\x1b[B", "\x1bOB", "j":
+			d.cursor++
+		case "\r", "\n":
+			if len(d.actions) > 0 && len(d.profiles) > 0 {
+				return d.actions[0], false
+			}
+		default:
+			for _, a := range d.actions {
+				if n == 1 && buf[0] == a.Key && len(d.profiles) > 0 {
+					return a, false
+				}
+			}
+		}
+		d.clamp()
+	}
+}
+
+// clamp keeps the cursor on a profile, or at 0 when there are none
+func (d *dashboard) clamp() {
+	if d.cursor >= len(d.profiles) {
+		d.cursor = len(d.profiles) - 1
+	}
+	if d.cursor < 0 {
+		d.cursor = 0
+	}
+}
+
+func (d *dashboard) draw(fd int) {
+	width, height, err := term.GetSize(fd)
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 100, 30
+	}
+	active := os.Getenv("AWS_PROFILE")
+
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	title := "AWS Multitool"
+	right := "AWS_PROFILE=" + active + "  " + time.Now().Format("15:04:05")
+	line(&b, width, Cyan+title+Reset+strings.Repeat(" ", max(1, width-len(title)-len(right)))+right)
+	line(&b, width, "")
+
+	header := []string{"", "PROFILE", "REGION", "ACCOUNT", "SOURCE", "EXPIRES"}
+	rows := [][]string{header}
+	for _, p := range d.profiles {
+		marker := " "
+		if p.Name == active {
+			marker = "*"
+		}
+		rows = append(rows, []string{marker, p.Name, p.Region, p.AccountID, p.Source, countdown(p.Expiration)})
+	}
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	// keep the cursor on screen, leaving room for the header and footer
+	visible := max(1, height-6)
+	offset := 0
+	if d.cursor >= visible {
+		offset = d.cursor - visible + 1
+	}
+	line(&b, width, Gray+pad(rows[0], widths)+Reset)
+	for i, row := range rows[1:] {
+		if i < offset || i >= offset+visible {
+			continue
+		}
+		text := pad(row, widths)
+		if color := expiryColor(d.profiles[i].Expiration); color != "" {
+			text = pad(row[:5], widths) + "  " + color + row[5] + Reset
+		}
+		if i == d.cursor {
+			text = reverse + pad(row, widths) + Reset
+		}
+		line(&b, width, text)
+	}
+	if len(d.profiles) == 0 {
+		line(&b, width, "  no profiles found")
+	}
+
+	line(&b, width, "")
+	keys := []string{"↑/↓ select"}
+	for i, a := range d.actions {
+		label := string(a.Key) + " " + a.Label
+		if i == 0 {
+			label = "enter/" + label
+		}
+		keys = append(keys, label)
+	}
+	line(&b, width, Gray+strings.Join(append(keys, "q quit"), "  ")+Reset)
+	if d.status != "" {
+		line(&b, width, d.status)
+	}
+	fmt.Print(b.String())
+}
+
+// line writes s cut to the terminal width, ending it the raw-mode way
+func line(b *strings.Builder, width int, s string) {
+	if visibleLen(s) > width {
+		s = truncate(s, width)
+	}
+	b.WriteString(s + "\x1b[K\r\n")
+}
+
+func pad(row []string, widths []int) string {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		cells[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+	}
+	return " " + strings.Join(cells, "  ")
+}
+
+// countdown shows how long credentials have left, to the second
+func countdown(expiration *time.Time) string {
+	if expiration == nil {
+		return ""
+	}
+	left := time.Until(*expiration).Truncate(time.Second)
+	if left <= 0 {
+		return "expired"
+	}
+	return "expires in " + left.String()
+}
+
+func expiryColor(expiration *time.Time) string {
+	if expiration == nil {
+		return ""
+	}
+	switch awsconfig.StateOf(*expiration) {
+	case awsconfig.Expired:
+		return Red
+	case awsconfig.ExpiringSoon:
+		return Yellow
+	}
+	return Green
+}
+
+// visibleLen counts the runes of s that are not part of escape sequences
+func visibleLen(s string) int {
+	n, escaped := 0, false
+	for _, r := range s {
+		switch {
+		case r == '\x1b':
+			escaped = true
+		case escaped:
+			escaped = r < '@' || r > '~' || r == '['
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// truncate cuts s to width visible runes, keeping escape sequences
+func truncate(s string, width int) string {
+	var b strings.Builder
+	n, escaped := 0, false
+	for _, r := range s {
+		switch {
+		case r == '\x1b':
+			escaped = true
+		case escaped:
+			escaped = r < '@' || r > '~' || r == '['
+		default:
+			if n == width {
+				continue
+			}
+			n++
+		}
+		b.WriteRune(r)
+	}
+	return b.String() + Reset
+}
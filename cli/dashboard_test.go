@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVisibleLen(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"plain", 5},
+		{Red + "red" + Reset, 3},
+		{reverse + " dev  eu-west-1" + Reset, 15},
+		{"↑/↓ select", 10},
+	}
+	for _, tt := range tests {
+		if got := visibleLen(tt.s); got != tt.want {
+			t.Errorf("visibleLen(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"profiles", 4, "prof" + Reset},
+		{Green + "expires" + Reset, 3, Green + "exp" + Reset + Reset},
+		{"↑/↓ select", 3, "↑/↓" + Reset},
+		{"short", 10, "short" + Reset},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.s, tt.width)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if n := visibleLen(got); n > tt.width {
+			t.Errorf("truncate(%q, %d) left %d visible runes", tt.s, tt.width, n)
+		}
+	}
+}
+
+func TestCountdown(t *testing.T) {
+	at := func(d time.Duration) *time.Time {
+		t := time.Now().Add(d)
+		return &t
+	}
+	tests := []struct {
+		expiration *time.Time
+		want       string
+	}{
+		{nil, ""},
+		{at(-time.Minute), "expired"},
+		{at(90*time.Second + 500*time.Millisecond), "expires in 1m30s"},
+		{at(2*time.Hour + 500*time.Millisecond), "expires in 2h0m0s"},
+	}
+	for _, tt := range tests {
+		if got := countdown(tt.expiration); got != tt.want {
+			t.Errorf("countdown(%v) = %q, want %q", tt.expiration, got, tt.want)
+		}
+	}
+}
+
+func TestDashboardClamp(t *testing.T) {
+	d := dashboard{profiles: make([]Profile, 5), cursor: 4}
+	d.profiles = d.profiles[:2]
+	d.clamp()
+	if d.cursor != 1 {
+		t.Errorf("cursor = %d after the list shrank to 2, want 1", d.cursor)
+	}
+	d.profiles = nil
+	d.clamp()
+	if d.cursor != 0 {
+		t.Errorf("cursor = %d with no profiles, want 0", d.cursor)
+	}
+}
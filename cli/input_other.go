@@ -0,0 +1,11 @@
+//go:build !unix
+
+package cli
+
+import "time"
+
+// waitInput cannot poll the console here, so reads simply block and the
+// dashboard redraws after each key
+func waitInput(fd int, timeout time.Duration) bool {
+	return true
+}
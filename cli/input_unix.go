@@ -0,0 +1,20 @@
+//go:build unix
+
+package cli
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitInput reports whether fd becomes readable within timeout, so the
+// dashboard can redraw its countdowns while no key is pressed
+func waitInput(fd int, timeout time.Duration) bool {
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, int(timeout/time.Millisecond))
+	if err == unix.EINTR {
+		return false
+	}
+	return err != nil || n > 0
+}
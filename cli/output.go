@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormats lists the values --output accepts. table is for people,
+// text is tab separated without a header, json and yaml follow the
+// schemas in schema.go.
+var OutputFormats = []string{"table", "text", "json", "yaml"}
+
+// OutputFormat is how Render prints, set from --output
+var OutputFormat = "table"
+
+// Rower is implemented by the schemas so they can be printed as tables
+type Rower interface {
+	Columns() []string
+	Row() []string
+}
+
+// CheckOutputFormat reports an error for an unknown --output value
+func CheckOutputFormat(format string) error {
+	for _, f := range OutputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(OutputFormats, ", "))
+}
+
+// Human reports whether output is meant for people, so commands know when
+// to add messages that would break a parser
+func Human() bool {
+	return OutputFormat == "table"
+}
+
+// Render prints v, a schema value or a slice of them, to stdout
+func Render(v interface{}) error {
+	return RenderTo(os.Stdout, OutputFormat, v)
+}
+
+// RenderTo prints v to w in format
+func RenderTo(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "table", "text":
+		rows, single, err := rowsOf(v)
+		if err != nil {
+			return err
+		}
+		if format == "text" {
+			for _, row := range rows {
+				fmt.Fprintln(w, strings.Join(row.Row(), "\t"))
+			}
+			return nil
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		if single {
+			// one value reads better as a list of fields
+			values := rows[0].Row()
+			for i, column := range rows[0].Columns() {
+				fmt.Fprintf(tw, "%s:\t%s\n", column, values[i])
+			}
+			return tw.Flush()
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(rows[0].Columns(), "\t")))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row.Row(), "\t"))
+		}
+		return tw.Flush()
+	}
+	return CheckOutputFormat(format)
+}
+
+// rowsOf flattens v into its rows and reports whether it was one value
+func rowsOf(v interface{}) ([]Rower, bool, error) {
+	if r, ok := v.(Rower); ok {
+		return []Rower{r}, true, nil
+	}
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Slice {
+		return nil, false, fmt.Errorf("cannot print %T as a table", v)
+	}
+	rows := make([]Rower, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		r, ok := value.Index(i).Interface().(Rower)
+		if !ok {
+			return nil, false, fmt.Errorf("cannot print %T as a table", v)
+		}
+		rows = append(rows, r)
+	}
+	return rows, false, nil
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTo(t *testing.T) {
+	profiles := []Profile{
+		{Name: "dev", Region: "eu-west-1", Source: "static keys", State: "none"},
+		{Name: "admin", Source: "assumes role", State: "none"},
+	}
+	tests := []struct {
+		format string
+		v      interface{}
+		want   string
+	}{
+		{"table", profiles, "PROFILE  REGION     SOURCE        EXPIRES\ndev      eu-west-1  static keys   \nadmin               assumes role  \n"},
+		{"text", profiles, "dev\teu-west-1\tstatic keys\t\nadmin\t\tassumes role\t\n"},
+		{"table", Identity{Account: "123", Arn: "arn", UserID: "id"}, "Profile:  \nAccount:  123\nARN:      arn\nUser ID:  id\n"},
+		{"json", []Profile{}, "[]\n"},
+		{"yaml", Login{URL: "u", Username: "me", Password: "pw"}, "url: u\nusername: me\npassword: pw\n"},
+	}
+	for _, test := range tests {
+		var b strings.Builder
+		if err := RenderTo(&b, test.format, test.v); err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+		if b.String() != test.want {
+			t.Errorf("%s: got\n%q\nwant\n%q", test.format, b.String(), test.want)
+		}
+	}
+
+	if err := RenderTo(&strings.Builder{}, "xml", profiles); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
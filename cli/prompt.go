@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"unicode/utf8"
 	"github.com/manifoldco/promptui"
+	"golang.org/x/term"
 )
 
 type PromptContent struct {
@@ -151,3 +154,89 @@ func PromptRepoName() (repo string, err error) {
 	}
 	return repo, err
 }
+
+// PromptOptional asks for a value that may be left empty, offering def
+func PromptOptional(label, def string) string {
+	prompt := promptui.Prompt{
+		Label:   label,
+		Default: def,
+	}
+	result, err := prompt.Run()
+	if err != nil {
+		fmt.Printf("Prompt failed %v\n", err)
+		os.Exit(1)
+	}
+	return result
+}
+
+// PromptSecret asks for a value without echoing it
+func PromptSecret(label string) string {
+	prompt := promptui.Prompt{
+		Label: label,
+		Mask:  '*',
+	}
+	result, err := prompt.Run()
+	if err != nil {
+		fmt.Printf("Prompt failed %v\n", err)
+		os.Exit(1)
+	}
+	return result
+}
+
+// ErrNoTerminal means a prompt was needed but nobody can answer it
+var ErrNoTerminal = errors.New("no terminal to prompt on")
+
+// ReadCode asks for a one-time code on the terminal without echoing it.
+// The prompt goes to stderr, so the code can be asked for by commands
+// whose stdout is captured, e.g. eval "$(aws-multitool export)".
+func ReadCode(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", ErrNoTerminal
+	}
+	fmt.Fprint(os.Stderr, label+": ")
+	code, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(code)), nil
+}
+
+// PromptConfirm asks a yes/no question, defaulting to no
+func PromptConfirm(label string) bool {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	return err == nil
+}
+
+// PromptSelect lets the user pick one of items
+func PromptSelect(label string, items []string) string {
+	prompt := promptui.Select{
+		Label: label,
+		Items: items,
+	}
+	_, result, err := prompt.Run()
+	if err != nil {
+		fmt.Printf("Prompt failed %v\n", err)
+		os.Exit(1)
+	}
+	return result
+}
+
+// FuzzyMatch reports whether the letters of input appear in target in
+// order, ignoring case and spaces, so "prdadm" finds "prod-admin"
+func FuzzyMatch(input, target string) bool {
+	target = strings.ToLower(target)
+	for _, r := range strings.ToLower(strings.ReplaceAll(input, " ", "")) {
+		i := strings.IndexRune(target, r)
+		if i < 0 {
+			return false
+		}
+		target = target[i+utf8.RuneLen(r):]
+	}
+	return true
+}
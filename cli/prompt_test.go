@@ -0,0 +1,21 @@
+package cli
+
+import "testing"
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		input, target string
+		want          bool
+	}{
+		{"", "anything", true},
+		{"prdadm", "prod-admin", true},
+		{"PROD adm", "prod-admin", true},
+		{"admprod", "prod-admin", false},
+		{"x", "prod-admin", false},
+	}
+	for _, tt := range tests {
+		if got := FuzzyMatch(tt.input, tt.target); got != tt.want {
+			t.Errorf("FuzzyMatch(%q, %q) = %v, want %v", tt.input, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"strconv"
+	"time"
+)
+
+// The schemas below are what --output json and yaml print. Add fields
+// freely, but do not rename or remove them: scripts depend on the keys.
+
+// Profile is one profile of the AWS files
+type Profile struct {
+	Name       string     `json:"name" yaml:"name"`
+	Region     string     `json:"region,omitempty" yaml:"region,omitempty"`
+	Source     string     `json:"source" yaml:"source"`
+	AccountID  string     `json:"account_id,omitempty" yaml:"account_id,omitempty"`
+	Expiration *time.Time `json:"expiration,omitempty" yaml:"expiration,omitempty"`
+	State      string     `json:"state" yaml:"state"`
+	Conflicts  []string   `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+	// Expires is Expiration for people, e.g. "expires in 1h5m"
+	Expires string `json:"-" yaml:"-"`
+}
+
+func (p Profile) Columns() []string {
+	return []string{"Profile", "Region", "Source", "Expires"}
+}
+
+func (p Profile) Row() []string {
+	return []string{p.Name, p.Region, p.Source, p.Expires}
+}
+
+// Credentials are keys for a profile, with the console login when the
+// provider hands one out
+type Credentials struct {
+	Profile         string     `json:"profile,omitempty" yaml:"profile,omitempty"`
+	AccessKeyID     string     `json:"access_key_id" yaml:"access_key_id"`
+	SecretAccessKey string     `json:"secret_access_key" yaml:"secret_access_key"`
+	SessionToken    string     `json:"session_token,omitempty" yaml:"session_token,omitempty"`
+	Region          string     `json:"region,omitempty" yaml:"region,omitempty"`
+	Expiration      *time.Time `json:"expiration,omitempty" yaml:"expiration,omitempty"`
+	Login           *Login     `json:"login,omitempty" yaml:"login,omitempty"`
+}
+
+func (c Credentials) Columns() []string {
+	columns := []string{"Profile", "Access Key ID", "Secret Access Key", "Session Token", "Region", "Expiration"}
+	if c.Login != nil {
+		columns = append(columns, "Console URL", "Username", "Password")
+	}
+	return columns
+}
+
+func (c Credentials) Row() []string {
+	row := []string{c.Profile, c.AccessKeyID, c.SecretAccessKey, c.SessionToken, c.Region, formatTime(c.Expiration)}
+	if c.Login != nil {
+		row = append(row, c.Login.URL, c.Login.Username, c.Login.Password)
+	}
+	return row
+}
+
+// Identity is who a profile authenticates as
+type Identity struct {
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account string `json:"account" yaml:"account"`
+	Arn     string `json:"arn" yaml:"arn"`
+	UserID  string `json:"user_id" yaml:"user_id"`
+}
+
+func (i Identity) Columns() []string {
+	return []string{"Profile", "Account", "ARN", "User ID"}
+}
+
+func (i Identity) Row() []string {
+	return []string{i.Profile, i.Account, i.Arn, i.UserID}
+}
+
+// ProfileIdentity is who one profile authenticates as, for whoami --all
+type ProfileIdentity struct {
+	Profile string `json:"profile" yaml:"profile"`
+	Account string `json:"account,omitempty" yaml:"account,omitempty"`
+	Arn     string `json:"arn,omitempty" yaml:"arn,omitempty"`
+	Alias   string `json:"alias,omitempty" yaml:"alias,omitempty"`
+	Source  string `json:"source" yaml:"source"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (i ProfileIdentity) Columns() []string {
+	return []string{"Profile", "Account", "Alias", "ARN", "Source", "Error"}
+}
+
+func (i ProfileIdentity) Row() []string {
+	return []string{i.Profile, i.Account, i.Alias, i.Arn, i.Source, i.Error}
+}
+
+// Console is the sign-in page of an account
+type Console struct {
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Account string `json:"account" yaml:"account"`
+	URL     string `json:"url" yaml:"url"`
+}
+
+func (c Console) Columns() []string {
+	return []string{"Profile", "Account", "URL"}
+}
+
+func (c Console) Row() []string {
+	return []string{c.Profile, c.Account, c.URL}
+}
+
+// Login is a website login kept in the credentials database
+type Login struct {
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	URL      string `json:"url" yaml:"url"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+func (l Login) Columns() []string {
+	return []string{"Name", "URL", "Username", "Password"}
+}
+
+func (l Login) Row() []string {
+	return []string{l.Name, l.URL, l.Username, l.Password}
+}
+
+// Change is one edit made to an AWS file
+type Change struct {
+	File    string `json:"file" yaml:"file"`
+	Section string `json:"section" yaml:"section"`
+	Key     string `json:"key,omitempty" yaml:"key,omitempty"`
+	Action  string `json:"action" yaml:"action"`
+}
+
+func (c Change) Columns() []string {
+	return []string{"Action", "File", "Section", "Key"}
+}
+
+func (c Change) Row() []string {
+	return []string{c.Action, c.File, c.Section, c.Key}
+}
+
+// Problem is one finding of lint
+type Problem struct {
+	File     string `json:"file" yaml:"file"`
+	Line     int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Severity string `json:"severity" yaml:"severity"`
+	Message  string `json:"message" yaml:"message"`
+	Fixable  bool   `json:"fixable" yaml:"fixable"`
+}
+
+func (p Problem) Columns() []string {
+	return []string{"Severity", "File", "Line", "Message", "Fixable"}
+}
+
+func (p Problem) Row() []string {
+	line := ""
+	if p.Line > 0 {
+		line = strconv.Itoa(p.Line)
+	}
+	return []string{p.Severity, p.File, line, p.Message, strconv.FormatBool(p.Fixable)}
+}
+
+// Setting is one of the tool's own settings and where its value came from
+type Setting struct {
+	Key    string `json:"key" yaml:"key"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+}
+
+func (s Setting) Columns() []string {
+	return []string{"Key", "Value", "Source"}
+}
+
+func (s Setting) Row() []string {
+	return []string{s.Key, s.Value, s.Source}
+}
+
+// Account is a sandbox provider seat and the profile it refreshes
+type Account struct {
+	Name     string `json:"name" yaml:"name"`
+	Provider string `json:"provider" yaml:"provider"`
+	Profile  string `json:"profile" yaml:"profile"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	State    string `json:"state" yaml:"state"`
+	// Expires is the profile's expiration for people
+	Expires string `json:"-" yaml:"-"`
+}
+
+func (a Account) Columns() []string {
+	return []string{"Account", "Provider", "Profile", "Username", "Expires"}
+}
+
+func (a Account) Row() []string {
+	return []string{a.Name, a.Provider, a.Profile, a.Username, a.Expires}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Local().Format(time.RFC3339)
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The shell wrapper points these at a temp file and its own shell, so a
+// profile switch made inside the tool can be applied to the calling shell
+// once the tool exits.
+const (
+	ShellOutEnv  = "AWS_MULTITOOL_SHELL_OUT"
+	ShellNameEnv = "AWS_MULTITOOL_SHELL"
+)
+
+// Shells lists the shells the integration supports
+var Shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// conflictingVars would override AWS_PROFILE if left set
+var conflictingVars = []string{
+	"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+	"AWS_SECURITY_TOKEN", "AWS_CREDENTIAL_EXPIRATION", "AWS_DEFAULT_PROFILE",
+}
+
+// DetectShell guesses the user's shell from the wrapper or $SHELL
+func DetectShell() string {
+	if shell := os.Getenv(ShellNameEnv); shell != "" {
+		return shell
+	}
+	switch shell := filepath.Base(os.Getenv("SHELL")); shell {
+	case "zsh", "fish":
+		return shell
+	case "pwsh", "powershell":
+		return "powershell"
+	}
+	if os.Getenv("PSModulePath") != "" && os.Getenv("SHELL") == "" {
+		return "powershell"
+	}
+	return "bash"
+}
+
+// ShellSwitch returns code that makes the calling shell use profile and
+// clears any keys exported earlier that would take precedence over it
+func ShellSwitch(shell, profile string) (string, error) {
+	var b strings.Builder
+	switch shell {
+	case "bash", "zsh":
+		fmt.Fprintf(&b, "export AWS_PROFILE='%s'\n", strings.ReplaceAll(profile, "'", `'\''`))
+		fmt.Fprintf(&b, "unset %s\n", strings.Join(conflictingVars, " "))
+	case "fish":
+		fmt.Fprintf(&b, "set -gx AWS_PROFILE %s\n", QuoteFish(profile))
+		fmt.Fprintf(&b, "set -e %s\n", strings.Join(conflictingVars, " "))
+	case "powershell":
+		fmt.Fprintf(&b, "$Env:AWS_PROFILE = '%s'\n", strings.ReplaceAll(profile, "'", "''"))
+		for _, v := range conflictingVars {
+			fmt.Fprintf(&b, "Remove-Item Env:%s -ErrorAction SilentlyContinue\n", v)
+		}
+	default:
+		return "", fmt.Errorf("unsupported shell %q, expected one of %s", shell, strings.Join(Shells, ", "))
+	}
+	return b.String(), nil
+}
+
+// fishQuoter escapes the two characters that stay special inside fish
+// single quotes
+var fishQuoter = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+// QuoteFish single-quotes s for fish, which knows no '\'' trick
+func QuoteFish(s string) string {
+	return "'" + fishQuoter.Replace(s) + "'"
+}
+
+// ExportToShell hands a profile switch to the shell wrapper, if the tool
+// was started through it. It reports whether the switch will reach the
+// calling shell.
+func ExportToShell(profile string) (bool, error) {
+	out := os.Getenv(ShellOutEnv)
+	if out == "" {
+		return false, nil
+	}
+	code, err := ShellSwitch(DetectShell(), profile)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(out, []byte(code), 0600)
+}
+
+// ShellInit returns the wrapper function and completions for shell. The
+// wrapper runs binary with ShellOutEnv set and sources whatever the tool
+// left there.
+func ShellInit(shell, binary string, commands, profileActions []string) (string, error) {
+	fn := strings.ReplaceAll(binary, "-", "_")
+	cmds := strings.Join(commands, " ")
+	actions := strings.Join(profileActions, " ")
+
+	switch shell {
+	case "bash", "zsh":
+		script := `{{bin}}() {
+  local out code
+  out="$(mktemp)" || return
+  {{OUT}}="$out" {{SHELL}}={{shell}} command {{bin}} "$@"
+  code=$?
+  [ -s "$out" ] && . "$out"
+  rm -f "$out"
+  return $code
+}
+
+_{{fn}}_complete() {
+  local cur="${COMP_WORDS[COMP_CWORD]}"
+  if [ "$COMP_CWORD" -eq 1 ]; then
+    COMPREPLY=( $(compgen -W "{{cmds}}" -- "$cur") )
+  elif [ "${COMP_WORDS[1]}" = "profile" ] && [ "$COMP_CWORD" -eq 2 ]; then
+    COMPREPLY=( $(compgen -W "{{actions}}" -- "$cur") )
+  elif [ "${COMP_WORDS[1]}" = "profile" ] && [ "${COMP_WORDS[2]}" = "use" ]; then
+    COMPREPLY=( $(compgen -W "$(command {{bin}} profile list --names 2>/dev/null)" -- "$cur") )
+  elif [ "${COMP_WORDS[1]}" = "env" ]; then
+    COMPREPLY=( $(compgen -W "$(command {{bin}} profile list --names 2>/dev/null)" -- "$cur") )
+  fi
+}
+`
+		if shell == "zsh" {
+			script += "autoload -U +X bashcompinit && bashcompinit\n"
+		}
+		script += "complete -F _{{fn}}_complete {{bin}}\n"
+		return fill(script, shell, binary, fn, cmds, actions), nil
+	case "fish":
+		return fill(`function {{bin}}
+    set -l out (mktemp)
+    env {{OUT}}=$out {{SHELL}}=fish {{bin}} $argv
+    set -l code $status
+    test -s $out; and source $out
+    rm -f $out
+    return $code
+end
+
+complete -c {{bin}} -f -n __fish_use_subcommand -a "{{cmds}}"
+complete -c {{bin}} -f -n "__fish_seen_subcommand_from profile; and not __fish_seen_subcommand_from {{actions}}" -a "{{actions}}"
+complete -c {{bin}} -f -n "__fish_seen_subcommand_from use env" -a "(command {{bin}} profile list --names 2>/dev/null)"
+`, shell, binary, fn, cmds, actions), nil
+	case "powershell":
+		return fill(`function {{bin}} {
+    $out = New-TemporaryFile
+    $exe = (Get-Command {{bin}} -CommandType Application | Select-Object -First 1).Source
+    $Env:{{OUT}} = $out.FullName
+    $Env:{{SHELL}} = 'powershell'
+    try { & $exe @args } finally {
+        Remove-Item Env:{{OUT}}, Env:{{SHELL}} -ErrorAction SilentlyContinue
+    }
+    if ((Get-Item $out).Length -gt 0) { Invoke-Expression (Get-Content -Raw $out) }
+    Remove-Item $out
+}
+
+Register-ArgumentCompleter -Native -CommandName {{bin}} -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = $commandAst.CommandElements | ForEach-Object { $_.ToString() }
+    if ($words.Count -le 2 -and $wordToComplete -ne '' -or $words.Count -eq 1) {
+        $candidates = '{{cmds}}' -split ' '
+    } elseif ($words[1] -eq 'profile' -and $words.Count -le 3) {
+        $candidates = '{{actions}}' -split ' '
+    } else {
+        $exe = (Get-Command {{bin}} -CommandType Application | Select-Object -First 1).Source
+        $candidates = & $exe profile list --names 2>$null
+    }
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } |
+        ForEach-Object { [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_) }
+}
+`, shell, binary, fn, cmds, actions), nil
+	}
+	return "", fmt.Errorf("unsupported shell %q, expected one of %s", shell, strings.Join(Shells, ", "))
+}
+
+func fill(script, shell, binary, fn, cmds, actions string) string {
+	return strings.NewReplacer(
+		"{{bin}}", binary,
+		"{{fn}}", fn,
+		"{{shell}}", shell,
+		"{{cmds}}", cmds,
+		"{{actions}}", actions,
+		"{{OUT}}", ShellOutEnv,
+		"{{SHELL}}", ShellNameEnv,
+	).Replace(script)
+}
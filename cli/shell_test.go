@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShellSwitch(t *testing.T) {
+	tests := []struct {
+		shell, profile string
+		want           []string
+	}{
+		{"bash", "dev", []string{"export AWS_PROFILE='dev'\n", "unset AWS_ACCESS_KEY_ID "}},
+		{"zsh", "it's", []string{`export AWS_PROFILE='it'\''s'` + "\n"}},
+		{"fish", "dev", []string{"set -gx AWS_PROFILE 'dev'\n", "set -e AWS_ACCESS_KEY_ID "}},
+		{"fish", `it's\`, []string{`set -gx AWS_PROFILE 'it\'s\\'` + "\n"}},
+		{"powershell", "it's", []string{"$Env:AWS_PROFILE = 'it''s'\n", "Remove-Item Env:AWS_SESSION_TOKEN -ErrorAction SilentlyContinue\n"}},
+	}
+	for _, tt := range tests {
+		got, err := ShellSwitch(tt.shell, tt.profile)
+		if err != nil {
+			t.Errorf("%s: %v", tt.shell, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("ShellSwitch(%q, %q) = %q, missing %q", tt.shell, tt.profile, got, want)
+			}
+		}
+	}
+	if _, err := ShellSwitch("tcsh", "dev"); err == nil {
+		t.Error("unsupported shells should be rejected")
+	}
+}
+
+func TestShellInit(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{"bash", []string{"aws-multitool() {", ShellOutEnv + `="$out" ` + ShellNameEnv + "=bash", "complete -F _aws_multitool_complete aws-multitool\n", `compgen -W "use list"`}},
+		{"zsh", []string{ShellNameEnv + "=zsh", "bashcompinit"}},
+		{"fish", []string{"function aws-multitool\n", "env " + ShellOutEnv + "=$out " + ShellNameEnv + "=fish", `-a "whoami expiry"`}},
+		{"powershell", []string{"function aws-multitool {", "$Env:" + ShellNameEnv + " = 'powershell'", "'use list' -split ' '"}},
+	}
+	for _, tt := range tests {
+		got, err := ShellInit(tt.shell, "aws-multitool", []string{"whoami", "expiry"}, []string{"use", "list"})
+		if err != nil {
+			t.Errorf("%s: %v", tt.shell, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s init is missing %q:\n%s", tt.shell, want, got)
+			}
+		}
+		if strings.Contains(got, "{{") {
+			t.Errorf("%s init has unfilled placeholders:\n%s", tt.shell, got)
+		}
+	}
+	if _, err := ShellInit("tcsh", "aws-multitool", nil, nil); err == nil {
+		t.Error("unsupported shells should be rejected")
+	}
+}
@@ -3,6 +3,8 @@ package cli
 import (
 	"fmt"
 	"github.com/rs/zerolog"
+	"io"
+	"os"
 )
 
 var Reset = "\033[0m"
@@ -22,32 +24,40 @@ func PrintIfErr(err error) {
 }
 
 
+// Success prints messages in green to stderr, only when the log level is
+// debug: they are diagnostics, and stdout may be read by another program
 func Success(message ...interface{}) {
+	if zerolog.GlobalLevel() == zerolog.DebugLevel {
+		printTo(os.Stderr, Green, message)
+	}
+}
 
-	//if log level is debug, print success messages
+// Debug prints messages to w, only when the log level is debug
+func Debug(w io.Writer, message ...interface{}) {
 	if zerolog.GlobalLevel() == zerolog.DebugLevel {
 		for _, msg := range message {
-			s, ok := msg.(string) // the "ok" boolean will flag success.
-			if ok {
-				fmt.Println(Green + string(s) + Reset)
-			} else {
-				fmt.Println(msg)
-			}
+			fmt.Fprintln(w, msg)
 		}
 	}
 }
 
+// Error prints messages in red to stderr whatever the log level, since
+// they report something the user has to know about
 func Error(message ...interface{}) {
-	//if log level is debug, print err messages
-	if zerolog.GlobalLevel() == zerolog.DebugLevel {
+	printTo(os.Stderr, Red, message)
+}
 
-		for _, msg := range message {
-			s, ok := msg.(string) // the "ok" boolean will flag success.
-			if ok {
-				fmt.Println(Red + string(s) + Reset)
-			} else {
-				fmt.Println(msg)
-			}
+// Warning prints messages in yellow to stderr whatever the log level
+func Warning(message ...interface{}) {
+	printTo(os.Stderr, Yellow, message)
+}
+
+func printTo(w io.Writer, color string, message []interface{}) {
+	for _, msg := range message {
+		if s, ok := msg.(string); ok {
+			fmt.Fprintln(w, color+s+Reset)
+		} else {
+			fmt.Fprintln(w, msg)
 		}
 	}
 }
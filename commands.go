@@ -0,0 +1,305 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/cli"
+	"aws-multitool/config"
+	"aws-multitool/core"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// command is one scriptable subcommand of the tool
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+// exitCode lets a command pick the process exit status without printing
+// an error, for commands whose status is the answer
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(c))
+}
+
+// commands is filled in by init() because the init command lists the
+// commands itself
+var commands []command
+
+func init() {
+	commands = []command{
+		{"profile", "profile use|list|add|copy|rename|delete|pin|unpin [flags]", "switch to or manage profiles", profileCommand},
+		{"sandbox", "sandbox refresh|stop|providers [--profile sandbox | --account name | --all]", "start or stop a sandbox, storing its credentials in a profile", sandboxCommand},
+		{"account", "account list|add|remove [--name alice] [--profile sandbox-alice]", "manage sandbox provider accounts and their profiles", accountCommand},
+		{"console", "console open|url [--profile name]", "open the AWS console signed in as a profile, or print its sign-in URL", consoleCommand},
+		{"creds", "creds set|get [flags]", "store or look up website logins", credsCommand},
+		{"whoami", "whoami [--profile name | --all]", "show the account and ARN behind a profile, or every profile", whoami},
+		{"export", "export [--profile name] [--sandbox [--account name]] [--format bash] [--out file]", "print credentials for shells, dotenv, JSON, tfvars or docker", exportCommand},
+		{"mfa-session", "mfa-session [--profile name] [--code 123456] [--watch]", "write an MFA session of an IAM user profile to <profile>-mfa", mfaSessionCommand},
+		{"credential-process", "credential-process [--profile sandbox]", "print sandbox credentials for credential_process, refreshing when expired", credentialProcessCommand},
+		{"serve-credentials", "serve-credentials [--profile name | --sandbox] [--addr 127.0.0.1:0]", "serve credentials over the ECS container credentials protocol", serveCommand},
+		{"expiry", "expiry [--profile name] [--warn 15m]", "exit 0 valid, 1 expiring, 2 expired, 3 unknown", expiryCommand},
+		{"lint", "lint [--fix]", "check the AWS files for problems", lintCommand},
+		{"restore", "restore [config|credentials] [backup]", "roll an AWS file back to a backup", restore},
+		{"env", "env [--shell bash] profile", "print shell code that switches to a profile, for eval", envCommand},
+		{"dashboard", "dashboard", "full-screen view of every profile with single-key actions", dashboardCommand},
+		{"config", "config get [key] | set key value | edit | path | migrate-db [old]", "show or change the tool's own settings", configCommand},
+		{"init", "init [bash|zsh|fish|powershell]", "print the shell wrapper and completions", initCommand},
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Without a command the interactive menu starts.")
+	fmt.Fprintln(out, "\nCommands:")
+	width := 0
+	for _, c := range commands {
+		if len(c.usage) > width {
+			width = len(c.usage)
+		}
+	}
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-*s  %s\n", width, c.usage, c.summary)
+	}
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+// runCommand runs args[0] and returns the process exit status
+func runCommand(args []string) int {
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	if *verbose {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+
+	if args[0] == "help" {
+		usage()
+		return 0
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		err := c.run(args[1:])
+		if code, ok := err.(exitCode); ok {
+			return int(code)
+		}
+		if err == flag.ErrHelp {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	usage()
+	return 2
+}
+
+// subcommand splits "use --flag x" into the action and its flags
+func subcommand(args []string, actions ...string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("expected one of %v", actions)
+	}
+	for _, a := range actions {
+		if a == args[0] {
+			return a, args[1:], nil
+		}
+	}
+	return "", nil, fmt.Errorf("unknown action %q, expected one of %v", args[0], actions)
+}
+
+func sandboxCommand(args []string) error {
+	action, args, err := subcommand(args, "refresh", "stop", "providers")
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("sandbox "+action, flag.ContinueOnError)
+	name := fs.String("profile", "sandbox", "profile the sandbox credentials belong to")
+	provider := fs.String("provider", "", "sandbox provider, defaults to the profile's "+awsconfig.ProviderKey)
+	account := fs.String("account", "", "provider account to use, instead of --profile")
+	all := fs.Bool("all", false, "refresh the sandbox of every account")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *account != "" {
+		a, ok := config.Current.Account(*account)
+		if !ok {
+			return fmt.Errorf("account %s not found", *account)
+		}
+		*name = a.Profile
+	}
+
+	switch action {
+	case "providers":
+		for _, name := range core.Providers() {
+			fmt.Println(name)
+		}
+		return nil
+	case "stop":
+		login, err := sandboxLogin(*name)
+		if err != nil {
+			return err
+		}
+		p, err := core.Provider(providerFor(*name, *provider), login, os.Stderr)
+		if err != nil {
+			return err
+		}
+		return p.Stop()
+	}
+	if *all {
+		return refreshAccounts()
+	}
+	return refreshSandbox(*name, *provider)
+}
+
+func consoleCommand(args []string) error {
+	action, args, err := subcommand(args, "open", "url")
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("console "+action, flag.ContinueOnError)
+	name := fs.String("profile", activeProfile(), "profile to open the console for, defaults to $AWS_PROFILE")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if action == "url" {
+		console, err := getAwsConsoleUrl(*name)
+		if err != nil {
+			return err
+		}
+		return cli.Render(console)
+	}
+	_, err = awsConsole(*name)
+	return err
+}
+
+func credsCommand(args []string) error {
+	action, args, err := subcommand(args, "set", "get")
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("creds "+action, flag.ContinueOnError)
+	db := fs.String("db", config.Current.DB, "SQLite database holding the logins")
+	name := fs.String("profile", "", "name the login is stored under")
+	url := fs.String("url", "", "login URL")
+	username := fs.String("username", "", "login username")
+	password := fs.String("password", "", "login password")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if action == "set" {
+		setCredentials(*name, *url, *username, *password, *db)
+		return nil
+	}
+	retrieveCredentials(*db, *name)
+	return nil
+}
+
+// whoami prints who the current (or given) profile authenticates as, or
+// with --all who every profile does
+func whoami(args []string) error {
+	fs := flag.NewFlagSet("whoami", flag.ContinueOnError)
+	name := fs.String("profile", "", "profile to check, defaults to $AWS_PROFILE")
+	all := fs.Bool("all", false, "check every profile")
+	parallel := fs.Int("parallel", 8, "with --all, how many profiles to check at once")
+	timeout := fs.Duration("timeout", 10*time.Second, "give up on each STS call after this long")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	stsTimeout = *timeout
+	if *all {
+		return whoamiAll(*parallel)
+	}
+	if *name != "" {
+		os.Setenv("AWS_PROFILE", *name)
+	}
+
+	identity, err := getCallerIdentity()
+	if err != nil {
+		return err
+	}
+	return cli.Render(cli.Identity{
+		Profile: activeProfile(),
+		Account: identity.Account,
+		Arn:     identity.Arn,
+		UserID:  identity.UserID,
+	})
+}
+
+func expiryCommand(args []string) error {
+	if code := expiry(args); code != expiryValid {
+		return exitCode(code)
+	}
+	return nil
+}
+
+func lintCommand(args []string) error {
+	ok, err := lint(args)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return exitCode(1)
+	}
+	return nil
+}
+
+// envCommand prints the code that switches the calling shell to a profile:
+// eval "$(aws-multitool env dev)"
+func envCommand(args []string) error {
+	fs := flag.NewFlagSet("env", flag.ContinueOnError)
+	shell := fs.String("shell", cli.DetectShell(), "bash, zsh, fish or powershell")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("env needs a profile name")
+	}
+
+	store, err := awsStore()
+	if err != nil {
+		return err
+	}
+	if _, err := store.Profile(fs.Arg(0)); err != nil {
+		return err
+	}
+	code, err := cli.ShellSwitch(*shell, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Print(code)
+	return nil
+}
+
+// initCommand prints the wrapper that lets menu switches reach the shell,
+// e.g. in ~/.bashrc: eval "$(aws-multitool init bash)"
+func initCommand(args []string) error {
+	shell := cli.DetectShell()
+	if len(args) > 0 {
+		shell = args[0]
+	}
+
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	script, err := cli.ShellInit(shell, binaryName, append(names, "help"), []string{"use", "list", "add", "copy", "rename", "delete", "pin", "unpin"})
+	if err != nil {
+		return err
+	}
+	fmt.Print(script)
+	return nil
+}
@@ -0,0 +1,289 @@
+// Package config holds the tool's own settings, read from a YAML file
+// under the XDG config dir and overridden by environment variables.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PathEnv overrides where the config file is read from
+const PathEnv = "AWS_MULTITOOL_CONFIG"
+
+// EnvPrefix starts the environment variable of every setting, e.g.
+// AWS_MULTITOOL_SANDBOX_URL for sandbox_url
+const EnvPrefix = "AWS_MULTITOOL_"
+
+// Config is every setting of the tool. Empty fields mean "not set".
+type Config struct {
+	AWSDir     string `yaml:"aws_dir,omitempty"`
+	Output     string `yaml:"output,omitempty"`
+	Browser    string `yaml:"browser,omitempty"`
+	SandboxURL string `yaml:"sandbox_url,omitempty"`
+	ACloudEnv  string `yaml:"acloud_env,omitempty"`
+	DB         string `yaml:"db,omitempty"`
+	// UI is what starts without a command: menu or dashboard
+	UI string `yaml:"ui,omitempty"`
+	// STSEndpoint replaces the regional STS endpoint, e.g. for a stub
+	STSEndpoint string `yaml:"sts_endpoint,omitempty"`
+	// IAMEndpoint replaces the global IAM endpoint
+	IAMEndpoint string `yaml:"iam_endpoint,omitempty"`
+	// FederationEndpoint replaces the AWS console sign-in federation
+	// endpoint
+	FederationEndpoint string `yaml:"federation_endpoint,omitempty"`
+	// Accounts only come from the file
+	Accounts []Account `yaml:"accounts,omitempty"`
+}
+
+// Account is a seat with a sandbox provider, bound to the AWS profile its
+// sandboxes are written to. Its login is kept in the credentials database
+// under the account name.
+type Account struct {
+	Name     string `yaml:"name"`
+	Provider string `yaml:"provider"`
+	Profile  string `yaml:"profile"`
+}
+
+// Account returns the account called name
+func (c Config) Account(name string) (Account, bool) {
+	for _, a := range c.Accounts {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Account{}, false
+}
+
+// AccountFor returns the account whose sandboxes go to profile
+func (c Config) AccountFor(profile string) (Account, bool) {
+	for _, a := range c.Accounts {
+		if a.Profile == profile {
+			return a, true
+		}
+	}
+	return Account{}, false
+}
+
+// MoveProfile binds the accounts of a renamed profile to its new name, or
+// removes them when to is empty because the profile was deleted. It
+// returns the accounts it changed.
+func (c *Config) MoveProfile(from, to string) []Account {
+	var moved []Account
+	kept := c.Accounts[:0]
+	for _, a := range c.Accounts {
+		if a.Profile == from {
+			moved = append(moved, a)
+			if to == "" {
+				continue
+			}
+			a.Profile = to
+		}
+		kept = append(kept, a)
+	}
+	c.Accounts = kept
+	return moved
+}
+
+// Where a setting's value came from
+const (
+	FromDefault = "default"
+	FromFile    = "file"
+	FromEnv     = "env"
+	FromFlag    = "flag"
+)
+
+// Current is the configuration in effect, set once at startup
+var Current = Defaults()
+
+// Sources records where each value of Current came from
+var Sources = map[string]string{}
+
+// fields maps each setting's key to its field
+func (c *Config) fields() map[string]*string {
+	return map[string]*string{
+		"aws_dir":             &c.AWSDir,
+		"output":              &c.Output,
+		"browser":             &c.Browser,
+		"sandbox_url":         &c.SandboxURL,
+		"acloud_env":          &c.ACloudEnv,
+		"db":                  &c.DB,
+		"ui":                  &c.UI,
+		"sts_endpoint":        &c.STSEndpoint,
+		"iam_endpoint":        &c.IAMEndpoint,
+		"federation_endpoint": &c.FederationEndpoint,
+	}
+}
+
+// Keys lists the setting names in order
+func Keys() []string {
+	var keys []string
+	for key := range new(Config).fields() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns the value of key
+func (c Config) Get(key string) (string, error) {
+	field, ok := c.fields()[key]
+	if !ok {
+		return "", unknownKey(key)
+	}
+	return *field, nil
+}
+
+// Set changes key to value; an empty value unsets it
+func (c *Config) Set(key, value string) error {
+	field, ok := c.fields()[key]
+	if !ok {
+		return unknownKey(key)
+	}
+	*field = value
+	return nil
+}
+
+func unknownKey(key string) error {
+	return fmt.Errorf("unknown setting %q, expected one of %s", key, strings.Join(Keys(), ", "))
+}
+
+// UIs are the values of the ui setting
+var UIs = []string{"menu", "dashboard"}
+
+// CheckUI rejects a ui setting that names no known interface
+func CheckUI(ui string) error {
+	for _, known := range UIs {
+		if ui == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown ui %q, expected one of %s", ui, strings.Join(UIs, ", "))
+}
+
+// LegacyDB is where the credentials database was kept before it moved to
+// the data dir: credentials.db in the working directory
+const LegacyDB = "credentials.db"
+
+// MigrateDB moves the database at from to path, but never over an existing
+// one. When the move fails the old file is where the logins still are.
+func MigrateDB(from, path string) error {
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s already exists", path)
+	}
+	if _, err := os.Stat(from); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.Rename(from, path)
+}
+
+// Defaults are the values used when nothing else sets them. An empty
+// browser lets rod find one.
+func Defaults() Config {
+	return Config{
+		Output:     "table",
+		SandboxURL: "https://learn.acloud.guru/cloud-playground/cloud-sandboxes",
+		ACloudEnv:  "./.env.acloud",
+		DB:         filepath.Join(dataDir(), "credentials.db"),
+		UI:         "menu",
+	}
+}
+
+// dataDir is $XDG_DATA_HOME/aws-multitool, for files the tool owns
+func dataDir() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "."
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "aws-multitool")
+}
+
+// Path is the config file: $AWS_MULTITOOL_CONFIG, or config.yaml in the
+// XDG config dir
+func Path() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "aws-multitool", "config.yaml"), nil
+}
+
+// ReadFile returns the settings in the file at path, none if it is missing
+func ReadFile(path string) (Config, error) {
+	var c Config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// WriteFile saves c to path, creating the directory
+func WriteFile(path string, c Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0600)
+}
+
+// Load layers the config file and then the environment over the
+// defaults, and reports where each value came from. Flags are applied
+// by the caller. A file that cannot be read is skipped and its error
+// returned along with the rest.
+func Load() (Config, map[string]string, error) {
+	c := Defaults()
+	sources := map[string]string{}
+	for _, key := range Keys() {
+		sources[key] = FromDefault
+	}
+
+	path, err := Path()
+	var file Config
+	if err == nil {
+		file, err = ReadFile(path)
+	}
+	for key, value := range file.fields() {
+		if *value != "" {
+			c.Set(key, *value)
+			sources[key] = FromFile
+		}
+	}
+	c.Accounts = file.Accounts
+
+	for _, key := range Keys() {
+		if value := os.Getenv(EnvPrefix + strings.ToUpper(key)); value != "" {
+			c.Set(key, value)
+			sources[key] = FromEnv
+		}
+	}
+	return c, sources, err
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv(PathEnv, path)
+	t.Setenv(EnvPrefix+"OUTPUT", "")
+	t.Setenv(EnvPrefix+"BROWSER", "")
+
+	file := Config{Output: "json", Browser: "/usr/bin/chromium", Accounts: []Account{
+		{Name: "alice", Provider: "acloudguru", Profile: "sandbox-alice"},
+	}}
+	if err := WriteFile(path, file); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvPrefix+"BROWSER", "/opt/chrome")
+
+	c, sources, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][2]string{
+		"output":      {"json", FromFile},
+		"browser":     {"/opt/chrome", FromEnv},
+		"sandbox_url": {Defaults().SandboxURL, FromDefault},
+	}
+	for key, w := range want {
+		got, _ := c.Get(key)
+		if got != w[0] || sources[key] != w[1] {
+			t.Errorf("%s = %q from %s, want %q from %s", key, got, sources[key], w[0], w[1])
+		}
+	}
+
+	if a, ok := c.AccountFor("sandbox-alice"); !ok || a.Name != "alice" {
+		t.Errorf("accounts were not loaded: %+v", c.Accounts)
+	}
+
+	if err := c.Set("nope", "x"); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+func TestCheckUI(t *testing.T) {
+	for _, ui := range UIs {
+		if err := CheckUI(ui); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := CheckUI("x"); err == nil {
+		t.Error("expected an error for an unknown ui")
+	}
+}
+
+func TestMigrateDB(t *testing.T) {
+	old := filepath.Join(t.TempDir(), LegacyDB)
+	path := filepath.Join(t.TempDir(), "aws-multitool", "credentials.db")
+
+	if err := MigrateDB(old, path); err == nil {
+		t.Fatal("expected an error with nothing to move")
+	}
+	if err := os.WriteFile(old, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateDB(old, path); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("moved database holds %q", data)
+	}
+
+	// once the new one exists another old one is left alone
+	if err := os.WriteFile(old, []byte("stray"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateDB(old, path); err == nil {
+		t.Error("an existing database must not be replaced")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("database was overwritten with %q", data)
+	}
+}
+
+func TestState(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	var s State
+	for _, name := range []string{"dev", "prod", "staging", "dev"} {
+		s.Use(name)
+	}
+	s.SetFavorite("ops", true)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := []int{s.Rank("ops"), s.Rank("dev"), s.Rank("staging"), s.Rank("prod"), s.Rank("other")}; !sort.IntsAreSorted(got) || got[0] == got[1] {
+		t.Errorf("ranks out of order: %v, state %+v", got, s)
+	}
+
+	s.SetFavorite("ops", false)
+	if s.IsFavorite("ops") {
+		t.Error("ops is still a favorite")
+	}
+}
+
+func TestMoveProfile(t *testing.T) {
+	s := State{Favorites: []string{"dev", "ops"}, Recent: []string{"prod", "dev", "ops"}}
+	if !s.MoveProfile("dev", "development") {
+		t.Error("dev was listed")
+	}
+	if !s.MoveProfile("prod", "ops") || !s.MoveProfile("ops", "") {
+		t.Error("prod and ops were listed")
+	}
+	if s.MoveProfile("missing", "x") {
+		t.Error("missing was not listed")
+	}
+	if !reflect.DeepEqual(s.Favorites, []string{"development"}) || !reflect.DeepEqual(s.Recent, []string{"development"}) {
+		t.Errorf("got favorites %v, recent %v", s.Favorites, s.Recent)
+	}
+
+	c := Config{Accounts: []Account{{Name: "alice", Profile: "sandbox-alice"}, {Name: "bob", Profile: "sandbox-bob"}}}
+	if moved := c.MoveProfile("sandbox-alice", "alice"); len(moved) != 1 || moved[0].Name != "alice" {
+		t.Errorf("moved %v", moved)
+	}
+	if moved := c.MoveProfile("sandbox-bob", ""); len(moved) != 1 {
+		t.Errorf("moved %v", moved)
+	}
+	if !reflect.DeepEqual(c.Accounts, []Account{{Name: "alice", Profile: "alice"}}) {
+		t.Errorf("accounts %+v", c.Accounts)
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxRecent is how many recently used profiles are remembered
+const maxRecent = 10
+
+// State is what the tool remembers between runs, as opposed to what the
+// user configures
+type State struct {
+	Favorites []string `yaml:"favorites,omitempty"`
+	// Recent holds the most recently used profile first
+	Recent []string `yaml:"recent,omitempty"`
+}
+
+// StatePath is state.yaml in $XDG_STATE_HOME/aws-multitool
+func StatePath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "state.yaml"
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "aws-multitool", "state.yaml")
+}
+
+// LoadState reads the state file, an empty State if there is none yet
+func LoadState() (State, error) {
+	var s State
+	data, err := os.ReadFile(StatePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return State{}, err
+	}
+	return s, nil
+}
+
+// Save writes the state file
+func (s State) Save() error {
+	path := StatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0600)
+}
+
+// Use moves profile to the front of the recently used list
+func (s *State) Use(profile string) {
+	s.Recent = append([]string{profile}, remove(s.Recent, profile)...)
+	if len(s.Recent) > maxRecent {
+		s.Recent = s.Recent[:maxRecent]
+	}
+}
+
+// SetFavorite pins or unpins profile
+func (s *State) SetFavorite(profile string, favorite bool) {
+	s.Favorites = remove(s.Favorites, profile)
+	if favorite {
+		s.Favorites = append(s.Favorites, profile)
+	}
+}
+
+// MoveProfile follows a renamed profile in the favorites and the recent
+// list, or drops it from both when to is empty because it was deleted.
+// It reports whether either list named the profile.
+func (s *State) MoveProfile(from, to string) bool {
+	listed := indexOf(s.Favorites, from) >= 0 || indexOf(s.Recent, from) >= 0
+	s.Favorites = rename(s.Favorites, from, to)
+	s.Recent = rename(s.Recent, from, to)
+	return listed
+}
+
+// IsFavorite reports whether profile is pinned
+func (s State) IsFavorite(profile string) bool {
+	return indexOf(s.Favorites, profile) >= 0
+}
+
+// Rank orders profiles for pickers: favorites first, then the recently
+// used ones, most recent first, then everything else
+func (s State) Rank(profile string) int {
+	if s.IsFavorite(profile) {
+		return 0
+	}
+	if i := indexOf(s.Recent, profile); i >= 0 {
+		return 1 + i
+	}
+	return 1 + maxRecent
+}
+
+func indexOf(list []string, item string) int {
+	for i, v := range list {
+		if v == item {
+			return i
+		}
+	}
+	return -1
+}
+
+func remove(list []string, item string) []string {
+	var kept []string
+	for _, v := range list {
+		if v != item {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// rename replaces from with to in list, or removes it when to is empty or
+// already listed
+func rename(list []string, from, to string) []string {
+	i := indexOf(list, from)
+	if i < 0 {
+		return list
+	}
+	if to == "" || indexOf(list, to) >= 0 {
+		return remove(list, from)
+	}
+	list[i] = to
+	return list
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// CacheDir is where the tool keeps credentials it has fetched, outside
+// the AWS files so they never shadow a credential_process
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "aws-multitool", "credentials"), nil
+}
+
+// CachePath is the file the credentials cached under name are kept in
+func CachePath(name string) (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(name)+".json"), nil
+}
+
+// LoadCachedCreds returns the credentials cached under name
+func LoadCachedCreds(name string) (LocalCreds, error) {
+	var creds LocalCreds
+	path, err := CachePath(name)
+	if err != nil {
+		return creds, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return creds, err
+	}
+	err = json.Unmarshal(data, &creds)
+	return creds, err
+}
+
+// SaveCachedCreds caches creds under name, readable only by the owner
+func SaveCachedCreds(name string, creds LocalCreds) error {
+	path, err := CachePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
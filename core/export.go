@@ -1,69 +1,172 @@
 package core
 
-// import (
-// 	"fmt"
-// 	// "aws-multitool/proxy"
-// 	"os"
-// )
-
-// // LocalCreds is a struct that holds the credentials for a user
-// type LocalCreds struct {
-// 	Path      string
-// 	User      string
-// 	KeyID     string
-// 	AccessKey string
-// }
+import (
+	"aws-multitool/cli"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
 
-// // Adds CLI credentials to the config file
-// func AppendAwsCredentials(creds LocalCreds) error {
-// 	newLine := fmt.Sprintf("\n\n[%s]\n", creds.User)
-// 	newLine += fmt.Sprintf("aws_access_key_id = %s\n", creds.KeyID)
-// 	newLine += fmt.Sprintf("aws_secret_access_key = %s\n", creds.AccessKey)
-// 	err := AppendLine(newLine, creds.Path)
+// LocalCreds is a struct that holds the credentials to export
+type LocalCreds struct {
+	Profile      string
+	KeyID        string
+	AccessKey    string
+	SessionToken string
+	Region       string
+	Expiration   time.Time
+}
 
-// 	return err
-// }
+// a renderer turns credentials into one export format
+type renderer func(creds LocalCreds) (string, error)
 
-// // Appends a line to a specified text file
-// func AppendLine(newLine string, path string) error {
-// 	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
-// 	if err != nil {
-// 		fmt.Println(err)
-// 		return err
-// 	}
-// 	_, err = fmt.Fprintln(f, newLine)
-// 	if err != nil {
-// 		fmt.Println(err)
-// 		f.Close()
-// 		return err
-// 	}
-// 	err = f.Close()
-// 	if err != nil {
-// 		fmt.Println(err)
-// 		return err
-// 	}
-// 	fmt.Println("file appended successfully")
-// 	return err
-// }
+var renderers = map[string]renderer{
+	"bash":       shellExports("export %s=%s", "unset %s", quoteSingle),
+	"zsh":        shellExports("export %s=%s", "unset %s", quoteSingle),
+	"fish":       shellExports("set -gx %s %s", "set -e %s", cli.QuoteFish),
+	"powershell": shellExports("$Env:%s = %s", "Remove-Item Env:%s -ErrorAction SilentlyContinue", quotePowerShell),
+	"dotenv":     envFile(quoteDouble),
+	"docker":     envFile(func(s string) string { return s }),
+	"json":       credentialProcess,
+	"tfvars":     tfvars,
+}
 
-//Creates a text file with exported 'policies'
-// func DocumentDownload(downloadKey string, policies []proxy.Policy) error {
-// 	//create a file with list of policies
-// 	file, err := os.Create(downloadKey + ".txt")
-// 	if err != nil {
-// 		return err
-// 	}
-// 	defer file.Close()
-// 	//write the policies to the file
-// 	for _, policy := range policies {
-// 		_, err := fmt.Fprintln(file, policy.CarrierID, policy.PolicyNumber)
-// 		if err != nil {
-// 			return err
-// 		}
-// 	}
-// 	return nil
-// }
+// ExportFormats lists the names accepted by Export
+func ExportFormats() []string {
+	formats := make([]string, 0, len(renderers))
+	for name := range renderers {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Export renders creds in the given format
+func Export(format string, creds LocalCreds) (string, error) {
+	render, ok := renderers[format]
+	if !ok {
+		return "", fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(ExportFormats(), ", "))
+	}
+	if creds.KeyID == "" || creds.AccessKey == "" {
+		return "", fmt.Errorf("profile %s has no access keys to export", creds.Profile)
+	}
+	return render(creds)
+}
+
+// WriteExport writes creds in the given format to path, readable only by
+// the owner since the file holds secrets
+func WriteExport(path, format string, creds LocalCreds) error {
+	out, err := Export(format, creds)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(out), 0600)
+}
+
+// envVars are the standard AWS environment variables for creds, in a
+// stable order. Empty values are kept so shells can unset them.
+func envVars(creds LocalCreds) [][2]string {
+	vars := [][2]string{
+		{"AWS_ACCESS_KEY_ID", creds.KeyID},
+		{"AWS_SECRET_ACCESS_KEY", creds.AccessKey},
+		{"AWS_SESSION_TOKEN", creds.SessionToken},
+	}
+	if creds.Region != "" {
+		vars = append(vars, [2]string{"AWS_REGION", creds.Region}, [2]string{"AWS_DEFAULT_REGION", creds.Region})
+	}
+	if !creds.Expiration.IsZero() {
+		vars = append(vars, [2]string{"AWS_CREDENTIAL_EXPIRATION", creds.Expiration.UTC().Format(time.RFC3339)})
+	}
+	return vars
+}
+
+// shellExports sets every variable, and unsets the session token when
+// there is none so a stale one from an earlier export does not linger
+func shellExports(set, unset string, quote func(string) string) renderer {
+	return func(creds LocalCreds) (string, error) {
+		var b strings.Builder
+		for _, v := range envVars(creds) {
+			if v[1] == "" {
+				fmt.Fprintf(&b, unset+"\n", v[0])
+				continue
+			}
+			fmt.Fprintf(&b, set+"\n", v[0], quote(v[1]))
+		}
+		return b.String(), nil
+	}
+}
+
+func envFile(quote func(string) string) renderer {
+	return func(creds LocalCreds) (string, error) {
+		var b strings.Builder
+		for _, v := range envVars(creds) {
+			if v[1] != "" {
+				fmt.Fprintf(&b, "%s=%s\n", v[0], quote(v[1]))
+			}
+		}
+		return b.String(), nil
+	}
+}
+
+// ProcessCredentials is the JSON document a credential_process prints
+type ProcessCredentials struct {
+	Version         int
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string `json:",omitempty"`
+	Expiration      string `json:",omitempty"`
+}
+
+func credentialProcess(creds LocalCreds) (string, error) {
+	doc := ProcessCredentials{
+		Version:         1,
+		AccessKeyId:     creds.KeyID,
+		SecretAccessKey: creds.AccessKey,
+		SessionToken:    creds.SessionToken,
+	}
+	if !creds.Expiration.IsZero() {
+		doc.Expiration = creds.Expiration.UTC().Format(time.RFC3339)
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	return string(out) + "\n", err
+}
+
+func tfvars(creds LocalCreds) (string, error) {
+	var b strings.Builder
+	vars := [][2]string{
+		{"aws_access_key", creds.KeyID},
+		{"aws_secret_key", creds.AccessKey},
+		{"aws_session_token", creds.SessionToken},
+		{"aws_region", creds.Region},
+	}
+	for _, v := range vars {
+		if v[1] != "" {
+			fmt.Fprintf(&b, "%s = %s\n", v[0], quoteHCL(v[1]))
+		}
+	}
+	return b.String(), nil
+}
+
+func quoteSingle(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func quotePowerShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func quoteDouble(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`)
+	return `"` + r.Replace(s) + `"`
+}
 
+func quoteHCL(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", "$${", "%{", "%%{")
+	return `"` + r.Replace(s) + `"`
+}
 
 // // screenshots the current browser window of the connection passed to it.
 // func ScreenShot(filename string, connect Connection) {
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExport(t *testing.T) {
+	creds := LocalCreds{
+		Profile:      "dev",
+		KeyID:        "ASIAEXAMPLE",
+		AccessKey:    `it's${secret}"`,
+		SessionToken: `to'ken\`,
+		Region:       "eu-west-1",
+		Expiration:   time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"bash", `export AWS_SECRET_ACCESS_KEY='it'\''s${secret}"'`},
+		{"fish", `set -gx AWS_REGION 'eu-west-1'`},
+		{"fish", `set -gx AWS_SESSION_TOKEN 'to\'ken\\'`},
+		{"powershell", `$Env:AWS_SECRET_ACCESS_KEY = 'it''s${secret}"'`},
+		{"dotenv", `AWS_SECRET_ACCESS_KEY="it's\${secret}\""`},
+		{"docker", `AWS_SECRET_ACCESS_KEY=it's${secret}"`},
+		{"json", `"Expiration": "2026-10-18T12:00:00Z"`},
+		{"tfvars", `aws_secret_key = "it's$${secret}\""`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			out, err := Export(test.format, creds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, test.want) {
+				t.Errorf("expected %q in:\n%s", test.want, out)
+			}
+		})
+	}
+
+	if _, err := Export("yaml", creds); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if out, _ := Export("bash", LocalCreds{KeyID: "AKIA", AccessKey: "s"}); !strings.Contains(out, "unset AWS_SESSION_TOKEN") {
+		t.Errorf("expected a stale session token to be unset:\n%s", out)
+	}
+}
+
+func TestCachedCreds(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := LoadCachedCreds("sandbox"); err == nil {
+		t.Fatal("expected an error before anything is cached")
+	}
+	want := LocalCreds{Profile: "sandbox", KeyID: "AKIAEXAMPLE", AccessKey: "secret", Expiration: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	if err := SaveCachedCreds("sandbox", want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadCachedCreds("sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
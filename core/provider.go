@@ -0,0 +1,80 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SandboxProvider is a service that hands out short-lived AWS accounts.
+// The methods are called in order: Login, StartSandbox, then
+// ExtractCredentials, after which Expiry is known. Stop ends the sandbox.
+type SandboxProvider interface {
+	Login() error
+	StartSandbox() error
+	ExtractCredentials() (SandboxCredentials, error)
+	Expiry() time.Time
+	Stop() error
+}
+
+// SandboxCredentials are a sandbox's keys and its console login
+type SandboxCredentials struct {
+	LocalCreds
+	Console WebsiteLogin
+}
+
+// DefaultProvider is used when neither a flag nor the profile names one
+const DefaultProvider = "acloudguru"
+
+// ProviderFactory creates a provider that signs in with login, or with
+// the provider's own default account when login is empty. The provider
+// writes its progress and diagnostics to log, never to stdout.
+type ProviderFactory func(login WebsiteLogin, log io.Writer) SandboxProvider
+
+var providers = map[string]ProviderFactory{}
+
+// RegisterProvider makes a provider available under name. Providers call
+// it from init.
+func RegisterProvider(name string, factory ProviderFactory) {
+	if _, ok := providers[name]; ok {
+		panic("sandbox provider registered twice: " + name)
+	}
+	providers[name] = factory
+}
+
+// Provider returns a new instance of the provider registered as name,
+// signing in with login and logging to log
+func Provider(name string, login WebsiteLogin, log io.Writer) (SandboxProvider, error) {
+	factory, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown sandbox provider %q, expected one of %s", name, strings.Join(Providers(), ", "))
+	}
+	return factory(login, log), nil
+}
+
+// Providers lists the registered provider names
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartSandbox runs a provider through login, start and extraction
+func StartSandbox(p SandboxProvider) (SandboxCredentials, error) {
+	if err := p.Login(); err != nil {
+		return SandboxCredentials{}, fmt.Errorf("logging in: %w", err)
+	}
+	if err := p.StartSandbox(); err != nil {
+		return SandboxCredentials{}, fmt.Errorf("starting the sandbox: %w", err)
+	}
+	creds, err := p.ExtractCredentials()
+	if err != nil {
+		return creds, fmt.Errorf("reading the sandbox credentials: %w", err)
+	}
+	return creds, nil
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	calls []string
+	fail  string
+}
+
+func (f *fakeProvider) step(name string) error {
+	f.calls = append(f.calls, name)
+	if f.fail == name {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func (f *fakeProvider) Login() error        { return f.step("login") }
+func (f *fakeProvider) StartSandbox() error { return f.step("start") }
+func (f *fakeProvider) Stop() error         { return f.step("stop") }
+func (f *fakeProvider) Expiry() time.Time   { return time.Time{} }
+func (f *fakeProvider) ExtractCredentials() (SandboxCredentials, error) {
+	return SandboxCredentials{LocalCreds: LocalCreds{KeyID: "AKIAEXAMPLE"}}, f.step("extract")
+}
+
+func TestProviderRegistry(t *testing.T) {
+	fake := &fakeProvider{}
+	RegisterProvider("fake", func(WebsiteLogin, io.Writer) SandboxProvider { return fake })
+	defer delete(providers, "fake")
+
+	p, err := Provider("fake", WebsiteLogin{}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := StartSandbox(p)
+	if err != nil || creds.KeyID != "AKIAEXAMPLE" {
+		t.Fatalf("got %+v, %v", creds, err)
+	}
+	if got := len(fake.calls); got != 3 {
+		t.Errorf("got calls %v, want login, start, extract", fake.calls)
+	}
+
+	fake.calls, fake.fail = nil, "start"
+	if _, err := StartSandbox(p); err == nil || len(fake.calls) != 2 {
+		t.Errorf("a failed start should stop the flow, got %v after %v", err, fake.calls)
+	}
+
+	if _, err := Provider("nope", WebsiteLogin{}, io.Discard); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
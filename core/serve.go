@@ -0,0 +1,70 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The env vars the SDKs read to find a container credentials endpoint
+const (
+	ContainerURIEnv   = "AWS_CONTAINER_CREDENTIALS_FULL_URI"
+	ContainerTokenEnv = "AWS_CONTAINER_AUTHORIZATION_TOKEN"
+)
+
+// ContainerCredentials is the document the ECS credentials endpoint returns
+type ContainerCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	Token           string `json:",omitempty"`
+	Expiration      string `json:",omitempty"`
+}
+
+// NewToken returns a random authorization token for CredentialsHandler
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CredentialsHandler serves the ECS container credentials protocol. Every
+// request must carry token in its Authorization header; fetch is called
+// for each one, one at a time, so refreshed keys are picked up.
+func CredentialsHandler(token string, fetch func() (LocalCreds, error)) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(token)) != 1 {
+			http.Error(w, "invalid authorization token", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		mu.Lock()
+		creds, err := fetch()
+		mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		doc := ContainerCredentials{
+			AccessKeyId:     creds.KeyID,
+			SecretAccessKey: creds.AccessKey,
+			Token:           creds.SessionToken,
+		}
+		if !creds.Expiration.IsZero() {
+			doc.Expiration = creds.Expiration.UTC().Format(time.RFC3339)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	})
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCredentialsHandler(t *testing.T) {
+	creds := LocalCreds{KeyID: "ASIAEXAMPLE", AccessKey: "secret", SessionToken: "token", Expiration: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	server := httptest.NewServer(CredentialsHandler("s3cret", func() (LocalCreds, error) { return creds, nil }))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without a token got status %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Authorization", "s3cret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got ContainerCredentials
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := ContainerCredentials{"ASIAEXAMPLE", "secret", "token", "2026-10-18T12:00:00Z"}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
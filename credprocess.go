@@ -0,0 +1,119 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/core"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// sandboxCreds starts (or reuses) a sandbox and caches its credentials
+// under profileName, so credential-process can serve them until they
+// expire. The provider reports its progress to progress.
+func sandboxCreds(progress io.Writer, profileName, provider string) (core.SandboxCredentials, error) {
+	unlock, err := lockSandbox(profileName)
+	if err != nil {
+		return core.SandboxCredentials{}, err
+	}
+	defer unlock()
+	return startSandbox(progress, profileName, provider)
+}
+
+// lockSandbox takes the lock on profileName's cache file. Every sandbox
+// start holds it, so two runs of the tool never start two sandboxes for
+// one profile.
+func lockSandbox(profileName string) (func(), error) {
+	path, err := core.CachePath(profileName)
+	if err != nil {
+		return nil, err
+	}
+	return awsconfig.LockFile(path)
+}
+
+// startSandbox is sandboxCreds for callers already holding the lock
+func startSandbox(progress io.Writer, profileName, provider string) (core.SandboxCredentials, error) {
+	login, err := sandboxLogin(profileName)
+	if err != nil {
+		return core.SandboxCredentials{}, err
+	}
+	creds, err := sandbox(progress, provider, login)
+	if err != nil {
+		return creds, err
+	}
+	creds.Profile = profileName
+	if creds.KeyID == "" || creds.AccessKey == "" {
+		return creds, fmt.Errorf("%s: no credentials found", provider)
+	}
+	if err := core.SaveCachedCreds(profileName, creds.LocalCreds); err != nil {
+		return creds, fmt.Errorf("caching sandbox credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// credentialProcessCommand implements the AWS credential_process contract
+// for a sandbox profile. In ~/.aws/config:
+//
+//	[profile sandbox]
+//	credential_process = aws-multitool credential-process --profile sandbox
+//
+// Only the JSON document goes to stdout; the scrape's chatter is sent to
+// stderr so the SDKs can parse the output. Keep the profile's keys out of
+// the credentials file, they would take precedence over the process.
+func credentialProcessCommand(args []string) error {
+	fs := flag.NewFlagSet("credential-process", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	name := fs.String("profile", "sandbox", "profile the credentials are cached under")
+	provider := fs.String("provider", "", "sandbox provider, defaults to the profile's "+awsconfig.ProviderKey)
+	before := fs.Duration("refresh-before", 5*time.Minute, "start a new sandbox when the cached one expires within this long")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	creds, err := currentSandboxCreds(os.Stderr, *name, *provider, *before)
+	if err != nil {
+		return err
+	}
+
+	out, err := core.Export("json", creds)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+	return nil
+}
+
+// currentSandboxCreds returns the credentials cached under profileName,
+// starting a new sandbox when they expire within before. The scrape
+// reports to progress, leaving stdout to the caller. Concurrent requests
+// and SDK processes wait on the sandbox lock for one sandbox instead of
+// each starting their own.
+func currentSandboxCreds(progress io.Writer, profileName, provider string, before time.Duration) (core.LocalCreds, error) {
+	if creds, ok := freshCachedCreds(profileName, before); ok {
+		return creds, nil
+	}
+
+	unlock, err := lockSandbox(profileName)
+	if err != nil {
+		return core.LocalCreds{}, err
+	}
+	defer unlock()
+	// whoever held the lock before us may have refreshed the cache
+	if creds, ok := freshCachedCreds(profileName, before); ok {
+		return creds, nil
+	}
+	fresh, err := startSandbox(progress, profileName, providerFor(profileName, provider))
+	return fresh.LocalCreds, err
+}
+
+// freshCachedCreds returns the credentials cached under profileName if
+// they are good for at least before
+func freshCachedCreds(profileName string, before time.Duration) (core.LocalCreds, bool) {
+	creds, err := core.LoadCachedCreds(profileName)
+	if err != nil || creds.Expiration.IsZero() || time.Until(creds.Expiration) < before {
+		return creds, false
+	}
+	return creds, true
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/cli"
+	"aws-multitool/core"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sandboxCounts records how many sandboxes a countingProvider started and
+// how often a start began while another was still running
+type sandboxCounts struct {
+	starts, running, overlaps int32
+}
+
+type countingProvider struct {
+	counts *sandboxCounts
+}
+
+func (p countingProvider) Login() error { return nil }
+func (p countingProvider) StartSandbox() error {
+	atomic.AddInt32(&p.counts.starts, 1)
+	if atomic.AddInt32(&p.counts.running, 1) > 1 {
+		atomic.AddInt32(&p.counts.overlaps, 1)
+	}
+	defer atomic.AddInt32(&p.counts.running, -1)
+	time.Sleep(50 * time.Millisecond)
+	return nil
+}
+func (p countingProvider) ExtractCredentials() (core.SandboxCredentials, error) {
+	return core.SandboxCredentials{LocalCreds: core.LocalCreds{
+		KeyID: "AKIASANDBOX", AccessKey: "secret", Expiration: time.Now().Add(time.Hour),
+	}}, nil
+}
+func (p countingProvider) Expiry() time.Time { return time.Time{} }
+func (p countingProvider) Stop() error       { return nil }
+
+// countingRuns names each run's provider, since providers cannot be
+// registered twice
+var countingRuns int32
+
+// useCountingProvider registers a countingProvider under a fresh name and
+// caches sandbox credentials in a temporary directory
+func useCountingProvider(t *testing.T) (string, *sandboxCounts) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	counts := &sandboxCounts{}
+	name := fmt.Sprintf("counting-%d", atomic.AddInt32(&countingRuns, 1))
+	core.RegisterProvider(name, func(core.WebsiteLogin, io.Writer) core.SandboxProvider {
+		return countingProvider{counts: counts}
+	})
+	return name, counts
+}
+
+func TestCurrentSandboxCredsStartsOneSandbox(t *testing.T) {
+	name, counts := useCountingProvider(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			creds, err := currentSandboxCreds(io.Discard, "sandbox-test", name, 5*time.Minute)
+			if err != nil || creds.KeyID != "AKIASANDBOX" {
+				t.Errorf("got %+v, %v", creds, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if counts.starts != 1 {
+		t.Errorf("concurrent refreshes started %d sandboxes, want 1", counts.starts)
+	}
+}
+
+func TestSandboxStartsTakeTurns(t *testing.T) {
+	name, counts := useCountingProvider(t)
+	useAWSDir(t, "", "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := refreshSandbox("sandbox-test", name); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := currentSandboxCreds(io.Discard, "sandbox-test", name, 5*time.Minute); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if counts.overlaps != 0 {
+		t.Errorf("%d sandboxes started while another was starting", counts.overlaps)
+	}
+}
+
+func TestCredentialProcessProfileKeepsNoKeys(t *testing.T) {
+	name, counts := useCountingProvider(t)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv(cli.ShellOutEnv, "")
+	store := useAWSDir(t, "[profile sandbox]\ncredential_process = aws-multitool credential-process\n", "")
+
+	// no expiry is recorded for the process, switching must not start one
+	if _, err := useProfile("sandbox"); err != nil {
+		t.Fatal(err)
+	}
+	if counts.starts != 0 {
+		t.Errorf("switching started %d sandboxes", counts.starts)
+	}
+
+	if err := refreshSandbox("sandbox", name); err != nil {
+		t.Fatal(err)
+	}
+	if creds, err := core.LoadCachedCreds("sandbox"); err != nil || creds.KeyID != "AKIASANDBOX" {
+		t.Errorf("cached %+v, %v", creds, err)
+	}
+	p, err := store.Profile("sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AccessKey != "" || p.Source.Kind != awsconfig.SourceProcess {
+		t.Errorf("refresh wrote keys over the credential_process: %+v", p)
+	}
+}
+
+func TestExportSandboxUsesProfileProvider(t *testing.T) {
+	name, counts := useCountingProvider(t)
+	useAWSDir(t, "[profile team]\n"+awsconfig.ProviderKey+" = "+name+"\n", "")
+	out := filepath.Join(t.TempDir(), "creds.json")
+
+	if err := exportCommand([]string{"--sandbox", "--profile", "team", "--format", "json", "--out", out}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(out); !strings.Contains(string(data), "AKIASANDBOX") || counts.starts != 1 {
+		t.Errorf("started %d sandboxes and exported:\n%s", counts.starts, data)
+	}
+	if creds, err := core.LoadCachedCreds("team"); err != nil || creds.KeyID != "AKIASANDBOX" {
+		t.Errorf("cached under team: %+v, %v", creds, err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/cli"
+	"aws-multitool/config"
+	"fmt"
+)
+
+// dashboard runs the full-screen profile view, with the same functions
+// behind its keys as the menu and the subcommands
+func dashboard() error {
+	return cli.Dashboard(dashboardProfiles, []cli.DashboardAction{
+		{Key: 's', Label: "switch", Run: func(name string) error {
+			_, err := useProfile(name)
+			return err
+		}},
+		{Key: 'r', Label: "refresh sandbox", Run: func(name string) error {
+			if !hasSandbox(name) {
+				return fmt.Errorf("profile %s has no sandbox provider or account", name)
+			}
+			return refreshSandbox(name, "")
+		}},
+		{Key: 'c', Label: "console", Run: func(name string) error {
+			_, err := awsConsole(name)
+			return err
+		}},
+		{Key: 'e', Label: "export", Run: func(name string) error {
+			return exportCommand([]string{"--profile", name})
+		}},
+	})
+}
+
+func dashboardProfiles() ([]cli.Profile, error) {
+	profiles, err := readAWSMasterFile()
+	if err != nil {
+		return nil, err
+	}
+	views := make([]cli.Profile, 0, len(profiles))
+	for _, p := range profiles {
+		views = append(views, profileView(p))
+	}
+	return views, nil
+}
+
+// defaultSandboxProfile is where sandboxes go unless told otherwise.
+// Older versions wrote it without a multitool_provider.
+const defaultSandboxProfile = "sandbox"
+
+// hasSandbox reports whether a profile is refreshed by a sandbox provider:
+// it is bound to an account, names a provider, or is the default sandbox
+// profile
+func hasSandbox(name string) bool {
+	if name == defaultSandboxProfile {
+		return true
+	}
+	if _, ok := config.Current.AccountFor(name); ok {
+		return true
+	}
+	store, err := awsStore()
+	if err != nil {
+		return false
+	}
+	p, err := store.Profile(name)
+	return err == nil && p.OtherProps[awsconfig.ProviderKey] != ""
+}
+
+func dashboardCommand(args []string) error {
+	return dashboard()
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/cli"
+	"aws-multitool/config"
+	"aws-multitool/core"
+	"aws-multitool/sts"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// profileCredentials turns a resolved profile into credentials that can
+// be exported or served, assuming its role if it has one
+func profileCredentials(p AWSMaster) (core.LocalCreds, error) {
+	if p.Source.Err != nil {
+		return core.LocalCreds{}, fmt.Errorf("profile %s: %w", p.Profile, p.Source.Err)
+	}
+	if p.Source.Kind == awsconfig.SourceAssumeRole {
+		return assumeRoleCredentials(p)
+	}
+	if p.AccessKey == "" || p.SecretKey == "" {
+		return core.LocalCreds{}, fmt.Errorf("profile %s has no access keys (%s)", p.Profile, p.Source)
+	}
+	return core.LocalCreds{
+		Profile:      p.Profile,
+		KeyID:        p.AccessKey,
+		AccessKey:    p.SecretKey,
+		SessionToken: p.SessionToken,
+		Region:       p.Region,
+		Expiration:   p.Expiration,
+	}, nil
+}
+
+// promptMFA is false where nobody can answer an MFA prompt, such as in
+// the parallel calls of whoami --all or the requests of serve
+var promptMFA = true
+
+// roleRefreshBefore is how long before expiry a cached role session is
+// replaced
+const roleRefreshBefore = 5 * time.Minute
+
+// assumeRoleCredentials assumes the role of p with the credentials of its
+// source profile, prompting for an MFA code when the role needs one.
+// Sessions are shared with the AWS CLI through its cache.
+func assumeRoleCredentials(p AWSMaster) (core.LocalCreds, error) {
+	role, err := awsconfig.RoleOptionsOf(p)
+	if err != nil {
+		return core.LocalCreds{}, err
+	}
+	store, err := awsStore()
+	if err != nil {
+		return core.LocalCreds{}, err
+	}
+
+	creds := core.LocalCreds{Profile: p.Profile, Region: p.Region}
+	key := role.CacheKey()
+	if cached, err := store.LoadCachedSession(key); err == nil && time.Until(cached.Expiration) > roleRefreshBefore {
+		creds.KeyID, creds.AccessKey, creds.SessionToken, creds.Expiration =
+			cached.AccessKeyID, cached.SecretAccessKey, cached.SessionToken, cached.Expiration
+		return creds, nil
+	}
+
+	source, err := roleSourceCredentials(store, p, role)
+	if err != nil {
+		return creds, err
+	}
+	if creds.Region == "" {
+		creds.Region = source.Region
+	}
+	in := sts.AssumeRoleInput{
+		RoleArn:         role.RoleArn,
+		RoleSessionName: role.RoleSessionName,
+		ExternalID:      role.ExternalID,
+		SerialNumber:    role.MFASerial,
+		Duration:        role.Duration,
+	}
+	if in.SerialNumber != "" {
+		needsMFA := fmt.Errorf("%s needs an MFA code, switch to profile %s to enter one", role.RoleArn, p.Profile)
+		if !promptMFA {
+			return creds, needsMFA
+		}
+		in.TokenCode, err = cli.ReadCode("MFA code for " + in.SerialNumber)
+		if errors.Is(err, cli.ErrNoTerminal) {
+			return creds, needsMFA
+		}
+		if err != nil {
+			return creds, err
+		}
+	}
+	session, err := stsClient(source).AssumeRole(in)
+	if err != nil {
+		return creds, fmt.Errorf("assuming %s for profile %s: %w", role.RoleArn, p.Profile, err)
+	}
+
+	err = store.SaveCachedSession(key, awsconfig.CachedSession{
+		AccessKeyID:     session.AccessKeyID,
+		SecretAccessKey: session.SecretAccessKey,
+		SessionToken:    session.SessionToken,
+		Expiration:      session.Expiration,
+	})
+	if err != nil {
+		cli.Error("caching the role session: " + err.Error())
+	}
+	creds.KeyID, creds.AccessKey, creds.SessionToken, creds.Expiration =
+		session.AccessKeyID, session.SecretAccessKey, session.SessionToken, session.Expiration
+	return creds, nil
+}
+
+// roleSourceCredentials are the credentials a profile assumes its role
+// with: its source profile's, its own keys, or the environment's
+func roleSourceCredentials(store awsconfig.Store, p AWSMaster, role awsconfig.RoleOptions) (core.LocalCreds, error) {
+	switch {
+	case role.SourceProfile == p.Profile:
+		return core.LocalCreds{Profile: p.Profile, KeyID: p.AccessKey, AccessKey: p.SecretKey, SessionToken: p.SessionToken, Region: p.Region}, nil
+	case role.SourceProfile != "":
+		source, err := store.Profile(role.SourceProfile)
+		if err != nil {
+			return core.LocalCreds{}, err
+		}
+		return profileCredentials(source)
+	case p.OtherProps["credential_source"] == "Environment":
+		creds := core.LocalCreds{
+			Profile:      p.Profile,
+			KeyID:        os.Getenv("AWS_ACCESS_KEY_ID"),
+			AccessKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			Region:       os.Getenv("AWS_REGION"),
+		}
+		if creds.KeyID == "" || creds.AccessKey == "" {
+			return creds, fmt.Errorf("profile %s takes its source credentials from the environment, but AWS_ACCESS_KEY_ID is not set", p.Profile)
+		}
+		return creds, nil
+	}
+	return core.LocalCreds{}, fmt.Errorf("profile %s: assuming a role with %s is not supported", p.Profile, p.Source)
+}
+
+// exportCommand prints a profile's credentials, or a freshly scraped
+// sandbox's, in one of the core export formats:
+// export [--profile name] [--sandbox [--account name]] [--format bash] [--out file]
+func exportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	name := fs.String("profile", "", "profile to export, or with --sandbox the profile the sandbox belongs to")
+	fromSandbox := fs.Bool("sandbox", false, "start a sandbox and export its fresh credentials")
+	account := fs.String("account", "", "provider account to start the sandbox with, instead of --profile")
+	provider := fs.String("provider", "", "sandbox provider for --sandbox, defaults to the profile's "+awsconfig.ProviderKey)
+	format := fs.String("format", "", "one of bash, zsh, fish, powershell, dotenv, docker, json, tfvars")
+	out := fs.String("out", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *account != "" {
+		if !*fromSandbox {
+			return errors.New("--account needs --sandbox")
+		}
+		a, ok := config.Current.Account(*account)
+		if !ok {
+			return fmt.Errorf("account %s not found", *account)
+		}
+		*name = a.Profile
+	}
+
+	var creds core.LocalCreds
+	if *fromSandbox {
+		if *name == "" {
+			*name = defaultSandboxProfile
+		}
+		fresh, err := sandboxCreds(os.Stderr, *name, providerFor(*name, *provider))
+		if err != nil {
+			return err
+		}
+		creds = fresh.LocalCreds
+	} else {
+		if *name == "" {
+			var err error
+			if *name, err = pickProfileName("Profile to export"); err != nil {
+				return err
+			}
+		}
+		store, err := awsStore()
+		if err != nil {
+			return err
+		}
+		p, err := store.Profile(*name)
+		if err != nil {
+			return err
+		}
+		if creds, err = profileCredentials(p); err != nil {
+			return err
+		}
+	}
+
+	if *format == "" {
+		*format = cli.PromptSelect("Export format", core.ExportFormats())
+	}
+	if *out != "" {
+		if err := core.WriteExport(*out, *format, creds); err != nil {
+			return err
+		}
+		fmt.Println("Wrote", *format, "credentials of", creds.Profile, "to", *out)
+		return nil
+	}
+	rendered, err := core.Export(*format, creds)
+	if err != nil {
+		return err
+	}
+	fmt.Print(rendered)
+	return nil
+}
@@ -1,14 +1,18 @@
 module aws-multitool
 
-go 1.19
+go 1.21
 
 require (
 	github.com/go-rod/rod v0.114.1
 	github.com/go-rod/stealth v0.4.9
 	github.com/joho/godotenv v1.5.1
 	github.com/manifoldco/promptui v0.9.0
+	github.com/mattn/go-sqlite3 v1.14.17
 	github.com/rs/zerolog v1.29.1
 	github.com/ysmood/leakless v0.8.0
+	golang.org/x/sys v0.10.0
+	golang.org/x/term v0.10.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -19,5 +23,4 @@ require (
 	github.com/ysmood/goob v0.4.0 // indirect
 	github.com/ysmood/got v0.34.1 // indirect
 	github.com/ysmood/gson v0.7.3 // indirect
-	golang.org/x/sys v0.10.0 // indirect
 )
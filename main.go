@@ -1,61 +1,103 @@
 package main
 
 import (
-	"aws-multitool/acloud"
+	_ "aws-multitool/acloud" // registers the acloudguru sandbox provider
+	"aws-multitool/awsconfig"
 	"aws-multitool/cli"
+	"aws-multitool/config"
 	"aws-multitool/core"
-	"bufio"
+	"aws-multitool/sts"
+	"flag"
 	"fmt"
+	"io"
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
 	"github.com/manifoldco/promptui"
 	"github.com/rs/zerolog"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 	"database/sql"
+	"errors"
 	"log"
+
+	_ "github.com/mattn/go-sqlite3" // Import SQLite driver
 )
 
-type AWSMaster struct {
-	Profile    string
-	AccessKey  string
-	SecretKey  string
-	Region     string
-	AccountID  string
-	OtherProps map[string]string
-}
+var awsDir = flag.String("aws-dir", "", "directory holding the AWS config and credentials files")
+// -v turns on debug output for subcommands; the menu always logs at debug
+var verbose = flag.Bool("v", false, "verbose output")
+
+// binaryName is what the shell integration calls the tool
+const binaryName = "aws-multitool"
+
+// AWSMaster is a profile merged from ~/.aws/config and ~/.aws/credentials
+type AWSMaster = awsconfig.AWSMaster
 
 func main() {
+	flag.Usage = usage
+	flag.Parse()
+	loadConfig()
+	if err := cli.CheckOutputFormat(*output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	cli.OutputFormat = *output
+
+	// subcommands run without the menu, for scripts
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args()))
+	}
+
+	if config.Current.UI == "dashboard" {
+		if err := dashboard(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	cli.Welcome()
 	ZeroLog()
+	warnExpiring()
 
 	for {
 
-		// Ask the user if they want to switch AWS profile or open the console
+		// Ask the user what they want to do, then run the matching menu entry
 		pSwitch := promptSwitch()
-
-		if pSwitch == "Switch Profile" {
-			// If the user chooses to switch profile, call the Profile function
-			profile()
-		} else if pSwitch == "Open AWS Console" {
-			// If the user chooses to open the AWS console, call the awsConsole function
-			awsConsole()
-		} else if pSwitch == "Set Credentials" {
-			// If the user chooses to set credentials, call the retrieveCredentials function
-			setCredentials("", "", "", "", "")
-		}else if pSwitch == "Retrieve Credentials" {
-			// If the user chooses to set credentials, call the retrieveCredentials function
-			retrieveCredentials("", "")
-		} else if pSwitch == "Exit" {
+		if pSwitch == "Exit" {
 			break
 		}
+		for _, item := range menu {
+			if item.label == pSwitch {
+				cli.PrintIfErr(item.run())
+			}
+		}
 	}
 
 }
 
+// menu maps each promptSwitch option to the same function its subcommand
+// runs, so the two cannot drift apart
+var menu = []struct {
+	label string
+	run   func() error
+}{
+	{"Switch Profile", func() error { _, err := profile(); return err }},
+	{"Dashboard", dashboard},
+	{"Refresh Sandbox", func() error { return refreshSandbox("sandbox", "") }},
+	{"Manage Profiles", func() error { return profileCommand(nil) }},
+	{"Open AWS Console", func() error { _, err := awsConsole(activeProfile()); return err }},
+	{"Who Am I", func() error { return whoami(nil) }},
+	{"Export Credentials", func() error { return exportCommand(nil) }},
+	{"Set Credentials", func() error { setCredentials("", "", "", "", ""); return nil }},
+	{"Retrieve Credentials", func() error { retrieveCredentials("", ""); return nil }},
+	{"Lint AWS Files", func() error { _, err := lint(nil); return err }},
+	{"Restore Backup", func() error { return restore(nil) }},
+}
+
 func ZeroLog() {
 	fmt.Println("os.Args : ", os.Args)
 	// default
@@ -71,9 +113,15 @@ func ZeroLog() {
 }
 
 func promptSwitch() string {
+	var items []string
+	for _, item := range menu {
+		items = append(items, item.label)
+	}
+
 	prompt := promptui.Select{
 		Label: "Choose an option",
-		Items: []string{"Switch Profile", "Open AWS Console", "Exit"},
+		Items: append(items, "Exit"),
+		Size:  len(items) + 1,
 	}
 
 	_, result, err := prompt.Run()
@@ -86,291 +134,564 @@ func promptSwitch() string {
 	return result
 }
 
-func profile() (m *AWSMaster) {
+// profile lets the user pick a profile from the AWS files and switches to it
+func profile() (*AWSMaster, error) {
 	credentials, err := readAWSMasterFile()
 	if err != nil {
-		fmt.Println("Error reading AWS credentials:", err)
-		return
-	}
-
-	var profileNames []string
-	for _, creds := range credentials {
-		profileNames = append(profileNames, creds.Profile)
+		return nil, fmt.Errorf("reading AWS credentials: %w", err)
 	}
+	items := pickerProfiles(credentials)
 
+	// show where each profile's credentials really come from
 	prompt := promptui.Select{
 		Label: "Select a profile : ",
-		Items: profileNames,
+		Items: items,
+		Size:  10,
+		Templates: &promptui.SelectTemplates{
+			Active:   "▸ {{ if .Favorite }}★{{ else }} {{ end }} {{ .Profile | cyan }}  {{ .Source | faint }}  {{ .Expiry | yellow }}",
+			Inactive: "  {{ if .Favorite }}★{{ else }} {{ end }} {{ .Profile }}  {{ .Source | faint }}  {{ .Expiry | yellow }}",
+			Selected: "{{ .Profile | green }}",
+			Details: `
+--------- {{ .Profile }} ----------
+{{ "Region:" | faint }}	{{ .Region }}
+{{ "Account:" | faint }}	{{ .AccountID }}
+{{ "Source:" | faint }}	{{ .Source }}
+{{ "Expires:" | faint }}	{{ .Expiry }}`,
+		},
+		Searcher: func(input string, index int) bool {
+			return cli.FuzzyMatch(input, items[index].Profile)
+		},
+	}
+
+	index, _, err := prompt.Run()
+	if err != nil {
+		return nil, err
 	}
+	return useProfile(items[index].Profile)
+}
+
+// pickerProfile is a profile as the picker lists it
+type pickerProfile struct {
+	AWSMaster
+	Favorite bool
+}
 
-	_, selected, err := prompt.Run()
+// pickerProfiles puts favorites first, then recently used profiles, then
+// the rest in file order
+func pickerProfiles(profiles []AWSMaster) []pickerProfile {
+	state, err := config.LoadState()
 	if err != nil {
-		fmt.Println("Prompt failed:", err)
-		return
+		cli.Error("reading favorites: " + err.Error())
+	}
+	items := make([]pickerProfile, 0, len(profiles))
+	for _, p := range profiles {
+		items = append(items, pickerProfile{AWSMaster: p, Favorite: state.IsFavorite(p.Profile)})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return state.Rank(items[i].Profile) < state.Rank(items[j].Profile)
+	})
+	return items
+}
+
+// useProfile switches to a profile, refreshing it first if its sandbox
+// has expired
+func useProfile(selected string) (*AWSMaster, error) {
+	store, err := awsStore()
+	if err != nil {
+		return nil, err
+	}
+	m, err := store.Profile(selected)
+	if err != nil {
+		return nil, err
 	}
 
-	if selected == "profile sandbox" {
-		// run Sandbox method
-		newCreds, err := sandbox()
-		if err != nil {
-			fmt.Println("Error logging into ACloudGuru:", err)
-			return
+	// warn about keys defined in both files
+	for _, c := range m.Conflicts {
+		cli.Warning(fmt.Sprintf("%s is set in both %s:%d and %s:%d, using the credentials file",
+			c.Key, c.Config.File, c.Config.Line, c.Credentials.File, c.Credentials.Line))
+	}
+
+	// profiles backed by a sandbox provider get a new sandbox once the
+	// old one runs out. credential_process profiles refresh themselves
+	// and record no expiry.
+	if hasSandbox(selected) && m.Source.Kind != awsconfig.SourceProcess {
+		switch awsconfig.StateOf(m.Expiration) {
+		case awsconfig.Expired, awsconfig.ExpiringSoon:
+			if err := refreshSandbox(selected, ""); err != nil {
+				return nil, err
+			}
 		}
+	}
 
-		err = replaceProfileCredentials("sandbox", newCreds.SandboxCredential.KeyID, newCreds.SandboxCredential.AccessKey)
+	// MFA sessions ask for a new code once they run out
+	if source := m.OtherProps[awsconfig.MFASourceKey]; source != "" && awsconfig.StateOf(m.Expiration) != awsconfig.Valid {
+		if _, err := mfaSession(source, "", "", mfaDuration); err != nil {
+			return nil, err
+		}
+	}
 
+	// assume roles now, so an MFA prompt comes up here and the session is
+	// cached for the AWS CLI too
+	if m.Source.Kind == awsconfig.SourceAssumeRole {
+		creds, err := profileCredentials(m)
 		if err != nil {
-			fmt.Println("Error updating AWS credentials:", err)
-			return
+			return nil, err
 		}
-		fmt.Println("Sandbox credentials updated successfully!")
+		fmt.Printf("Assumed %s, %s\n", m.Source.RoleArn, awsconfig.DescribeExpiry(creds.Expiration))
 	}
 
-	//set environment for $AWS_PROFILE
+	//set environment for $AWS_PROFILE, and for the calling shell when
+	//the tool runs through the shell integration
 	os.Setenv("AWS_PROFILE", selected)
+	rememberProfile(selected)
+	exported, err := cli.ExportToShell(selected)
+	if err != nil {
+		return nil, err
+	}
+	if exported {
+		fmt.Println("Using profile", selected)
+	} else {
+		fmt.Printf("Using profile %s in this process only. Run eval \"$(%s env %s)\" or set up %s init to switch your shell\n",
+			selected, binaryName, selected, binaryName)
+	}
 
-	//set credentials
-	// setCreds(os.Getenv("AWS_PROFILE"), consoleURL, "", "")
+	return &m, nil
+}
 
-	return &AWSMaster{
-		Profile:   selected,
-		AccessKey: credentials[0].AccessKey,
-		SecretKey: credentials[0].SecretKey,
-		Region:    credentials[0].Region,
+// refreshSandbox starts a sandbox with the profile's provider, or the
+// one given, and writes its credentials to profileName
+func refreshSandbox(profileName, provider string) error {
+	provider = providerFor(profileName, provider)
+	creds, err := sandboxCreds(os.Stderr, profileName, provider)
+	if err != nil {
+		return err
+	}
+	if cli.Human() {
+		view := credentialsView(creds.LocalCreds)
+		view.Login = &cli.Login{URL: creds.Console.Url, Username: creds.Console.Username, Password: creds.Console.Password}
+		cli.PrintIfErr(cli.Render(view))
+	}
+
+	// keys in the credentials file would take precedence over a
+	// credential_process, which serves the cached credentials instead
+	if usesCredentialProcess(profileName) {
+		return printChanges(nil, "Cached the sandbox credentials for the credential_process of "+profileName)
+	}
+	changes, err := replaceProfileCredentials(awsconfig.ProfileUpdate{
+		Profile:    profileName,
+		AccessKey:  creds.KeyID,
+		SecretKey:  creds.AccessKey,
+		Expiration: creds.Expiration,
+		Region:     creds.Region,
+		Output:     "json",
+		Settings:   map[string]string{awsconfig.ProviderKey: provider},
+	})
+	if err != nil {
+		return fmt.Errorf("updating AWS credentials: %w", err)
 	}
+	return printChanges(changes, "Sandbox credentials already up to date")
 }
 
-func sandbox() (acloud.ACloudProvider, error) {
-	var p acloud.ACloudProvider
-	login, err := acloud.ACloudLogin(p)
-	// p.Connection = connect
-	p.ACloudEnv.Url = login.Url
-	p.ACloudEnv.Username = login.Username
-	p.ACloudEnv.Password = login.Password
+// usesCredentialProcess reports whether profileName gets its credentials
+// from a credential_process
+func usesCredentialProcess(profileName string) bool {
+	store, err := awsStore()
 	if err != nil {
-		fmt.Println("Error logging into ACloudGuru:", err)
-		return p, err
+		return false
 	}
+	p, err := store.Profile(profileName)
+	return err == nil && p.Source.Kind == awsconfig.SourceProcess
+}
 
-	p, err = acloud.ConnectBrowser(p)
-	cli.PrintIfErr(err)
-	cli.Success("environment : ", p.ACloudEnv)
-
-	// log browser
-	cli.Success("Browser : ", p.Connection.Browser)
-
-	// //login to acloud
-	p.Connection, err = core.Login(core.WebsiteLogin{p.ACloudEnv.Url, p.ACloudEnv.Username, p.ACloudEnv.Password}, p.Connection.Browser)
-	cli.PrintIfErr(err)
-	cli.Success("A Cloud Provider : ", p)
-
-	time.Sleep(1 * time.Second)
-
-	//scrape credentials
-	elems, err := acloud.Sandbox(p.Connection, p.ACloudEnv.Download_key)
-	cli.PrintIfErr(err)
-	cli.Success("rod html elements : ", elems)
+// sandbox starts a sandbox with the named provider, signed in as login.
+// The provider reports what it is doing to progress.
+func sandbox(progress io.Writer, providerName string, login core.WebsiteLogin) (core.SandboxCredentials, error) {
+	p, err := core.Provider(providerName, login, progress)
+	if err != nil {
+		return core.SandboxCredentials{}, err
+	}
+	creds, err := core.StartSandbox(p)
+	if err != nil {
+		return creds, fmt.Errorf("%s: %w", providerName, err)
+	}
+	return creds, nil
+}
 
-	//copy credentials to clipboard
-	creds, err := acloud.SimpleCopy(elems)
-	cli.PrintIfErr(err)
-	acloud.DisplayCreds(creds)
+// providerFor names the sandbox provider of a profile: the one given,
+// else that of the account bound to the profile, else the profile's
+// multitool_provider, else the default
+func providerFor(profileName, provider string) string {
+	if provider != "" {
+		return provider
+	}
+	if a, ok := config.Current.AccountFor(profileName); ok && a.Provider != "" {
+		return a.Provider
+	}
+	if store, err := awsStore(); err == nil {
+		if p, err := store.Profile(profileName); err == nil && p.OtherProps[awsconfig.ProviderKey] != "" {
+			return p.OtherProps[awsconfig.ProviderKey]
+		}
+	}
+	return core.DefaultProvider
+}
 
-	//save provider
-	p.SandboxCredential = creds
-	return p, err
+// awsStore locates the AWS files, honoring --aws-dir and the AWS env vars
+func awsStore() (awsconfig.Store, error) {
+	if *awsDir != "" {
+		return awsconfig.Locate(*awsDir)
+	}
+	// a configured aws_dir only stands in for ~/.aws, the AWS env vars
+	// still win over it
+	return awsconfig.LocateIn(config.Current.AWSDir)
 }
 
 func readAWSMasterFile() ([]AWSMaster, error) {
-	var credentials []AWSMaster
-
-	homeDir, err := os.UserHomeDir()
+	store, err := awsStore()
 	if err != nil {
 		return nil, err
 	}
+	return store.Profiles()
+}
 
-	configFile := filepath.Join(homeDir, ".aws", "config")
-	file, err := os.Open(configFile)
+func replaceProfileCredentials(update awsconfig.ProfileUpdate) ([]awsconfig.Change, error) {
+	store, err := awsStore()
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	currentProfile := ""
-	var currentCreds AWSMaster
+	return store.UpsertProfile(update)
+}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+// restore rolls an AWS file back to one of its backups:
+// restore [config|credentials] [backup name]
+func restore(args []string) error {
+	store, err := awsStore()
+	if err != nil {
+		return err
+	}
 
-		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			// New profile
-			if currentProfile != "" {
-				credentials = append(credentials, currentCreds)
-			}
-			currentProfile = line[1 : len(line)-1]
-			currentCreds = AWSMaster{Profile: currentProfile}
-		} else if strings.Contains(line, "=") {
-			// Key-value pair
-			parts := strings.SplitN(line, "=", 2)
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-
-			switch key {
-			case "aws_access_key_id":
-				currentCreds.AccessKey = value
-			case "aws_secret_access_key":
-				currentCreds.SecretKey = value
-			case "region":
-				currentCreds.Region = value
-			default:
-				if currentCreds.OtherProps == nil {
-					currentCreds.OtherProps = make(map[string]string)
-				}
-				currentCreds.OtherProps[key] = value
-			}
+	target := ""
+	if len(args) > 0 {
+		target = args[0]
+	} else {
+		prompt := promptui.Select{
+			Label: "Which file do you want to restore?",
+			Items: []string{"credentials", "config"},
+		}
+		if _, target, err = prompt.Run(); err != nil {
+			return err
 		}
 	}
 
-	// Append the last profile
-	if currentProfile != "" {
-		credentials = append(credentials, currentCreds)
+	var path string
+	switch target {
+	case "config":
+		path = store.ConfigPath
+	case "credentials":
+		path = store.CredentialsPath
+	default:
+		return fmt.Errorf("unknown file %q, expected config or credentials", target)
 	}
 
-	return credentials, scanner.Err()
-}
-
-func replaceProfileCredentials(profileName, awsAccessKeyID, awsSecretAccessKey string) error {
-	homeDir, err := os.UserHomeDir()
+	backups, err := awsconfig.Backups(path)
 	if err != nil {
 		return err
 	}
+	if len(backups) == 0 {
+		return fmt.Errorf("no backups of %s", path)
+	}
 
-	credentialsFile := filepath.Join(homeDir, ".aws", "credentials")
-
-	// Open the credentials file in read mode
-	file, err := os.Open(credentialsFile)
-	if err != nil {
-		return err
+	chosen := -1
+	if len(args) > 1 {
+		for i, b := range backups {
+			if filepath.Base(b.Path) == args[1] || b.Path == args[1] {
+				chosen = i
+			}
+		}
+		if chosen < 0 {
+			return fmt.Errorf("no backup named %s", args[1])
+		}
+	} else {
+		var labels []string
+		for _, b := range backups {
+			labels = append(labels, b.Time.Local().Format(time.RFC1123)+"  "+filepath.Base(b.Path))
+		}
+		prompt := promptui.Select{
+			Label: "Restore " + path + " from : ",
+			Items: labels,
+		}
+		if chosen, _, err = prompt.Run(); err != nil {
+			return err
+		}
 	}
-	defer file.Close()
 
-	// Create a temporary file to write the modified contents
-	tmpFile, err := os.CreateTemp("", "aws_credentials")
-	if err != nil {
+	if err := awsconfig.Restore(path, backups[chosen]); err != nil {
 		return err
 	}
-	defer tmpFile.Close()
+	fmt.Println("Restored", path, "from", backups[chosen].Path)
+	return nil
+}
 
-	scanner := bufio.NewScanner(file)
-	inProfile := false
+// lint reports problems in the AWS files and, with --fix, repairs the
+// safe ones. It returns false when errors remain.
+func lint(args []string) (bool, error) {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fix := fs.Bool("fix", false, "repair the problems marked fixable")
+	if err := fs.Parse(args); err != nil {
+		return false, err
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	store, err := awsStore()
+	if err != nil {
+		return false, err
+	}
 
-		if strings.HasPrefix(line, "["+profileName+"]") {
-			inProfile = true
-		} else if inProfile && strings.HasPrefix(line, "[") {
-			inProfile = false
+	if *fix {
+		changes, err := store.Fix()
+		if err != nil {
+			return false, err
 		}
-
-		if inProfile {
-			if strings.HasPrefix(line, "aws_access_key_id") {
-				line = fmt.Sprintf("aws_access_key_id = %s", awsAccessKeyID)
-			} else if strings.HasPrefix(line, "aws_secret_access_key") {
-				line = fmt.Sprintf("aws_secret_access_key = %s", awsSecretAccessKey)
+		if cli.Human() {
+			for _, change := range changes {
+				fmt.Println(cli.Green + "fixed " + cli.Reset + change.String())
 			}
 		}
+	}
 
-		_, err = fmt.Fprintln(tmpFile, line)
-		if err != nil {
-			return err
+	problems, err := store.Lint()
+	if err != nil {
+		return false, err
+	}
+	ok := true
+	views := []cli.Problem{}
+	for _, p := range problems {
+		if p.Severity == awsconfig.SeverityError {
+			ok = false
 		}
+		views = append(views, cli.Problem(p))
+	}
+	if len(problems) == 0 && cli.Human() {
+		fmt.Println(cli.Green + "No problems found" + cli.Reset)
+		return ok, nil
 	}
+	return ok, cli.Render(views)
+}
 
-	if err := scanner.Err(); err != nil {
-		return err
+// warnExpiring prints a warning for every profile whose temporary
+// credentials are expired or about to expire
+func warnExpiring() {
+	profiles, err := readAWSMasterFile()
+	if err != nil {
+		return
+	}
+	for _, p := range profiles {
+		switch awsconfig.StateOf(p.Expiration) {
+		case awsconfig.ExpiringSoon:
+			cli.Warning("Warning: credentials of profile " + p.Profile + " " + p.Expiry())
+		case awsconfig.Expired:
+			cli.Error("Warning: credentials of profile " + p.Profile + " " + p.Expiry())
+		}
 	}
+}
+
+// Exit codes of the expiry command, for scripts
+const (
+	expiryValid   = 0
+	expirySoon    = 1
+	expiryExpired = 2
+	expiryUnknown = 3
+	expiryFailed  = 4
+)
 
-	// Replace the original credentials file with the temporary file
-	err = os.Rename(tmpFile.Name(), credentialsFile)
+// expiry reports when a profile's credentials run out and returns the
+// matching exit code: expiry [--profile name] [--warn 15m]
+func expiry(args []string) int {
+	fs := flag.NewFlagSet("expiry", flag.ContinueOnError)
+	name := fs.String("profile", os.Getenv("AWS_PROFILE"), "profile to check")
+	warn := fs.Duration("warn", awsconfig.WarnBefore, "how early to report credentials as expiring")
+	if err := fs.Parse(args); err != nil {
+		return expiryFailed
+	}
+	if *name == "" {
+		*name = "default"
+	}
+	awsconfig.WarnBefore = *warn
+
+	store, err := awsStore()
 	if err != nil {
-		return err
+		fmt.Fprintln(os.Stderr, "Error locating AWS files:", err)
+		return expiryFailed
+	}
+	p, err := store.Profile(*name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return expiryFailed
 	}
 
-	return nil
+	state := awsconfig.StateOf(p.Expiration)
+	if !cli.Human() {
+		if err := cli.Render(profileView(p)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return expiryFailed
+		}
+	}
+	switch state {
+	case awsconfig.NoExpiry:
+		if cli.Human() {
+			fmt.Println(p.Profile + ": no expiration recorded")
+		}
+		return expiryUnknown
+	case awsconfig.ExpiringSoon:
+		if cli.Human() {
+			fmt.Println(cli.Yellow + p.Profile + ": " + p.Expiry() + cli.Reset)
+		}
+		return expirySoon
+	case awsconfig.Expired:
+		if cli.Human() {
+			fmt.Println(cli.Red + p.Profile + ": " + p.Expiry() + cli.Reset)
+		}
+		return expiryExpired
+	}
+	if cli.Human() {
+		fmt.Println(cli.Green + p.Profile + ": " + p.Expiry() + cli.Reset)
+	}
+	return expiryValid
+}
+
+// activeProfile is $AWS_PROFILE, or default like the AWS CLI
+func activeProfile() string {
+	if name := os.Getenv("AWS_PROFILE"); name != "" {
+		return name
+	}
+	return "default"
+}
+
+// stsTimeout bounds each STS and IAM call
+var stsTimeout = 30 * time.Second
+
+// stsClient calls STS and IAM with creds, at the configured endpoints
+func stsClient(creds core.LocalCreds) *sts.Client {
+	client := sts.New(sts.Credentials{
+		AccessKeyID:     creds.KeyID,
+		SecretAccessKey: creds.AccessKey,
+		SessionToken:    creds.SessionToken,
+	}, creds.Region)
+	client.Endpoint = config.Current.STSEndpoint
+	client.IAMEndpoint = config.Current.IAMEndpoint
+	client.HTTPClient.Timeout = stsTimeout
+	return client
 }
 
-func getAwsConsoleUrl() (consoleURL string) {
-	cmd := exec.Command("aws", "sts", "get-caller-identity", "--query", "Account", "--output", "text")
+// activeCredentials are the credentials of the active profile
+func activeCredentials() (core.LocalCreds, error) {
+	return credentialsOf(activeProfile())
+}
 
-	output, err := cmd.Output()
+// credentialsOf are the credentials of the named profile
+func credentialsOf(name string) (core.LocalCreds, error) {
+	store, err := awsStore()
 	if err != nil {
-		fmt.Println("Error getting AWS account ID:", err)
-		return
+		return core.LocalCreds{}, err
+	}
+	p, err := store.Profile(name)
+	if err != nil {
+		return core.LocalCreds{}, err
 	}
+	return profileCredentials(p)
+}
 
-	accountIdSlice := strings.TrimSpace(string(output))
-	if accountIdSlice == "" {
-		fmt.Println("Failed to get the AWS account ID.")
-		return
+// getCallerIdentity asks STS who the active profile's credentials belong to
+func getCallerIdentity() (sts.CallerIdentity, error) {
+	creds, err := activeCredentials()
+	if err != nil {
+		return sts.CallerIdentity{}, err
 	}
-	// print account id
-	fmt.Println("Retrieved Account ID : ", accountIdSlice)
+	return callerIdentity(creds)
+}
 
-	accountID := string(accountIdSlice)
-	consoleURL = fmt.Sprintf("https://%s.signin.aws.amazon.com/console", accountID)
-	return consoleURL
+func callerIdentity(creds core.LocalCreds) (sts.CallerIdentity, error) {
+	identity, err := stsClient(creds).GetCallerIdentity()
+	if err != nil {
+		return identity, fmt.Errorf("getting the caller identity of profile %s: %w", creds.Profile, err)
+	}
+	if identity.Account == "" {
+		return identity, errors.New("failed to get the AWS account ID")
+	}
+	return identity, nil
 }
 
-func awsConsole() (connection core.Connection) {
+// federationPolicy lets a federated console session do whatever the
+// keys it was made from may do
+const federationPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"*","Resource":"*"}]}`
 
-	// print the current env var for AWS_PROFILE
-	fmt.Println("AWS_PROFILE : ", os.Getenv("AWS_PROFILE"))
+// consoleCreds are credentials the federation endpoint accepts: temporary
+// ones as they are, long-term keys swapped for a federation token
+func consoleCreds(creds core.LocalCreds) (sts.Credentials, error) {
+	if creds.SessionToken != "" {
+		return sts.Credentials{AccessKeyID: creds.KeyID, SecretAccessKey: creds.AccessKey, SessionToken: creds.SessionToken}, nil
+	}
+	temp, err := stsClient(creds).GetFederationToken(binaryName, federationPolicy, 12*time.Hour)
+	if err != nil {
+		return temp, fmt.Errorf("getting a federation token for profile %s: %w", creds.Profile, err)
+	}
+	return temp, nil
+}
 
-	consoleURL := getAwsConsoleUrl()
+// getAwsConsoleUrl returns a console URL that is already signed in as
+// profile, valid for 15 minutes
+func getAwsConsoleUrl(profile string) (console cli.Console, err error) {
+	creds, err := credentialsOf(profile)
+	if err != nil {
+		return console, err
+	}
+	identity, err := callerIdentity(creds)
+	if err != nil {
+		return console, err
+	}
+	temp, err := consoleCreds(creds)
+	if err != nil {
+		return console, err
+	}
+	url, err := sts.SigninURL(config.Current.FederationEndpoint, temp, sts.ConsoleDestination(creds.Region))
+	if err != nil {
+		return console, fmt.Errorf("signing in to the console: %w", err)
+	}
+	return cli.Console{
+		Profile: creds.Profile,
+		Account: identity.Account,
+		URL:     url,
+	}, nil
+}
 
-	fmt.Println("Navigating to AWS Management Console page..." + consoleURL)
+// awsConsole opens the console in a browser, signed in as profile
+func awsConsole(profile string) (connection core.Connection, err error) {
+	console, err := getAwsConsoleUrl(profile)
+	if err != nil {
+		return connection, err
+	}
+	if cli.Human() {
+		fmt.Printf("Opening the console of %s (%s)\n", console.Profile, console.Account)
+	}
 
-	u := launcher.New().Bin("/Applications/Google Chrome.app/Contents/MacOS/Google Chrome").Headless(false).MustLaunch()
+	bin := config.Current.Browser
+	if bin == "" {
+		var found bool
+		if bin, found = launcher.LookPath(); !found {
+			return connection, errors.New("no browser found, set one with: config set browser /path/to/chrome")
+		}
+	}
+	u := launcher.New().Bin(bin).Headless(false).MustLaunch()
 	browser := rod.New().ControlURL(u).MustConnect()
 
-	// //login to acloud
-	connection, err := core.Login(core.WebsiteLogin{consoleURL, "", ""}, browser)
-	cli.PrintIfErr(err)
+	connection = core.Connect(browser, console.URL)
 	cli.Success("connection : ", connection)
-	return connection
+	return connection, nil
 }
 
-const dbName = "credentials.db"
-
 func setCredentials(profileName, url, username, password, dbName string) {
 
 
 	if dbName == "" {
-		fmt.Print("Database Name: ")
-		fmt.Scanln(&dbName)
-	}
-
-
-	db, err := sql.Open("sqlite3", dbName)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer db.Close()
-
-	createTableSQL := `
-		CREATE TABLE IF NOT EXISTS credentials (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			profileName TEXT,
-			url TEXT,
-			username TEXT,
-			password TEXT
-		);
-		`
-	_, err = db.Exec(createTableSQL)
-	if err != nil {
-		log.Fatal(err)
+		dbName = config.Current.DB
 	}
 
 	fmt.Println("Please enter your credentials:")
@@ -395,6 +716,39 @@ func setCredentials(profileName, url, username, password, dbName string) {
 		fmt.Scanln(&password)
 	}
 
+	if err := storeLogin(dbName, profileName, url, username, password); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Credentials saved successfully!")
+
+}
+
+// storeLogin adds a login to the credentials database, creating it if
+// needed
+func storeLogin(dbName, profileName, url, username, password string) error {
+	if err := os.MkdirAll(filepath.Dir(dbName), 0700); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite3", dbName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	createTableSQL := `
+		CREATE TABLE IF NOT EXISTS credentials (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			profileName TEXT,
+			url TEXT,
+			username TEXT,
+			password TEXT
+		);
+		`
+	if _, err := db.Exec(createTableSQL); err != nil {
+		return err
+	}
+
 	// Cleanse input to prevent SQL injection
 	profileName = strings.TrimSpace(profileName)
 	url = strings.TrimSpace(url)
@@ -404,44 +758,59 @@ func setCredentials(profileName, url, username, password, dbName string) {
 	// Insert the credentials into the database
 	insertSQL := "INSERT INTO credentials (profileName, url, username, password) VALUES (?, ?, ?, ?);"
 	_, err = db.Exec(insertSQL, profileName, url, username, password)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	fmt.Println("Credentials saved successfully!")
-
+	return err
 }
 
 func retrieveCredentials(dbName, profileName string) (url, username, password string) {
+	if dbName == "" {
+		dbName = config.Current.DB
+	}
+
 	if profileName == "" {
 		fmt.Print("Profile Name: ")
 		fmt.Scanln(&profileName)
 	}
 
-	
-	db, err := sql.Open("sqlite3", dbName)
-    if err != nil {
-        log.Fatal(err)
+    if cli.Human() {
+        fmt.Printf("\nRetrieving stored credentials for profile '%s':\n", profileName)
     }
-    defer db.Close()
-
-    fmt.Printf("\nRetrieving stored credentials for profile '%s':\n", profileName)
-    rows, err := db.Query("SELECT url, username, password FROM credentials WHERE profileName = ?;", profileName)
+    logins, err := queryLogins(dbName, profileName)
     if err != nil {
         log.Fatal(err)
     }
-    defer rows.Close()
+    cli.PrintIfErr(cli.Render(logins))
 
-    // Initialize variables to store retrieved values
     var retrievedURL, retrievedUsername, retrievedPassword string
-
-    for rows.Next() {
-        if err := rows.Scan(&retrievedURL, &retrievedUsername, &retrievedPassword); err != nil {
-            log.Fatal(err)
-        }
-        fmt.Printf("URL: %s\nUsername: %s\nPassword: %s\n\n", retrievedURL, retrievedUsername, retrievedPassword)
+    if len(logins) > 0 {
+        last := logins[len(logins)-1]
+        retrievedURL, retrievedUsername, retrievedPassword = last.URL, last.Username, last.Password
     }
 
     // Return the values retrieved from the database
     return retrievedURL, retrievedUsername, retrievedPassword
 }
+
+// queryLogins returns the logins stored under profileName, oldest first
+func queryLogins(dbName, profileName string) ([]cli.Login, error) {
+	db, err := sql.Open("sqlite3", dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT url, username, password FROM credentials WHERE profileName = ?;", profileName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logins := []cli.Login{}
+	for rows.Next() {
+		login := cli.Login{Name: profileName}
+		if err := rows.Scan(&login.URL, &login.Username, &login.Password); err != nil {
+			return nil, err
+		}
+		logins = append(logins, login)
+	}
+	return logins, rows.Err()
+}
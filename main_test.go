@@ -2,14 +2,19 @@ package main
 
 import (
 	"database/sql"
-	_ "github.com/mattn/go-sqlite3" // Import SQLite driver
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"aws-multitool/awsconfig"
+	_ "github.com/mattn/go-sqlite3" // Import SQLite driver
 )
 
 func TestSetCreds(t *testing.T) {
 	// Call the setup function to initialize the test database
-	setupSetCredsTest(t)
-	// defer cleanup() // Ensure the test database is cleaned up after the test
+	dbName, cleanup := setupSetCredsTest(t)
+	defer cleanup()
 
 	tests := []struct {
 		profileName string
@@ -24,16 +29,25 @@ func TestSetCreds(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.profileName, func(t *testing.T) {
 			// Call the setCreds function with test data and the test database
-			setCreds(test.profileName, test.url, test.username, test.password)
+			setCredentials(test.profileName, test.url, test.username, test.password, dbName)
 
-			// Rest of your test logic
+			logins, err := queryLogins(dbName, test.profileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(logins) != 1 || logins[0].URL != test.url || logins[0].Username != test.username || logins[0].Password != test.password {
+				t.Errorf("stored logins for %s: %+v", test.profileName, logins)
+			}
 		})
 	}
 }
 
-func setupSetCredsTest(t *testing.T) (*sql.DB, func()) {
+// setupSetCredsTest creates a test database under a temporary directory and
+// returns its path
+func setupSetCredsTest(t *testing.T) (string, func()) {
 	// Create and initialize the test database
-	db, err := sql.Open("sqlite3", "credentials.db")
+	dbName := filepath.Join(t.TempDir(), "credentials.db")
+	db, err := sql.Open("sqlite3", dbName)
 	if err != nil {
 		t.Fatalf("error opening test database: %v", err)
 	}
@@ -56,17 +70,18 @@ func setupSetCredsTest(t *testing.T) (*sql.DB, func()) {
 	// Return a cleanup function to close the test database
 	cleanup := func() {
 		db.Close()
-		// Add any additional cleanup steps, if necessary
 	}
 
-	return db, cleanup
+	return dbName, cleanup
 }
 
 func TestRetrieveCredentials(t *testing.T) {
-	// Replace with your test database file name
-	testDBName := "credentials.db"
+	testDBName, cleanup := setupSetCredsTest(t)
+	defer cleanup()
+	if err := storeLogin(testDBName, "TestProfile1", "http://example.com", "testuser1", "testpass1"); err != nil {
+		t.Fatal(err)
+	}
 
-	// Assuming that you have previously stored credentials in the test database
 	tests := []struct {
 		profileName   string
 		expectedError bool // Expect an error for a non-existent profile
@@ -95,3 +110,38 @@ func TestRetrieveCredentials(t *testing.T) {
 		})
 	}
 }
+
+func TestExpiryExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	soon := time.Now().Add(5 * time.Minute).UTC().Format(time.RFC3339)
+	later := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	credentials := "[valid]\naws_access_key_id = A\n" + awsconfig.ExpirationKey + " = " + later +
+		"\n\n[soon]\naws_access_key_id = A\n" + awsconfig.ExpirationKey + " = " + soon +
+		"\n\n[gone]\naws_access_key_id = A\n" + awsconfig.ExpirationKey + " = " + past +
+		"\n\n[static]\naws_access_key_id = A\n"
+	if err := os.WriteFile(filepath.Join(dir, "credentials"), []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(d string) { *awsDir = d }(*awsDir)
+	*awsDir = dir
+	defer func(d time.Duration) { awsconfig.WarnBefore = d }(awsconfig.WarnBefore)
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"--profile", "valid"}, expiryValid},
+		{[]string{"--profile", "soon"}, expirySoon},
+		{[]string{"--profile", "valid", "--warn", "3h"}, expirySoon},
+		{[]string{"--profile", "gone"}, expiryExpired},
+		{[]string{"--profile", "static"}, expiryUnknown},
+		{[]string{"--profile", "missing"}, expiryFailed},
+		{[]string{"--warn", "soon"}, expiryFailed},
+	}
+	for _, test := range tests {
+		if got := expiry(test.args); got != test.want {
+			t.Errorf("expiry %v = %d, want %d", test.args, got, test.want)
+		}
+	}
+}
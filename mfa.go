@@ -0,0 +1,126 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/cli"
+	"aws-multitool/sts"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// mfaSuffix is appended to an IAM user profile's name to get the name of
+// the profile holding its MFA session
+const mfaSuffix = "-mfa"
+
+// mfaDuration is how long MFA sessions last unless --duration says
+// otherwise
+const mfaDuration = 12 * time.Hour
+
+// mfaSessionCommand writes an MFA-authenticated session of an IAM user
+// profile to <profile>-mfa:
+// mfa-session [--profile name] [--serial arn] [--code 123456] [--watch]
+func mfaSessionCommand(args []string) error {
+	fs := flag.NewFlagSet("mfa-session", flag.ContinueOnError)
+	name := fs.String("profile", activeProfile(), "IAM user profile with long-term keys")
+	serial := fs.String("serial", "", "MFA device ARN, defaults to the profile's mfa_serial")
+	code := fs.String("code", "", "current MFA code, asked for when empty")
+	duration := fs.Duration("duration", mfaDuration, "how long the session lasts, 15m to 36h")
+	watch := fs.Bool("watch", false, "keep running and ask for a new code before the session expires")
+	before := fs.Duration("refresh-before", 5*time.Minute, "with --watch, how long before expiry to renew")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *watch && *duration <= *before {
+		return fmt.Errorf("--duration %s must be longer than --refresh-before %s, or --watch renews without pause", *duration, *before)
+	}
+
+	for {
+		expiration, err := mfaSession(*name, *serial, *code, *duration)
+		if err != nil || !*watch {
+			return err
+		}
+		// a code is only good once
+		*code = ""
+		renew := expiration.Add(-*before)
+		fmt.Println("Renewing the session at", renew.Local().Format(time.Kitchen))
+		time.Sleep(time.Until(renew))
+	}
+}
+
+// mfaSession trades the long-term keys of an IAM user profile and an MFA
+// code for a session, writes it to <profile>-mfa and returns its expiry
+func mfaSession(name, serial, code string, duration time.Duration) (time.Time, error) {
+	store, err := awsStore()
+	if err != nil {
+		return time.Time{}, err
+	}
+	p, err := store.Profile(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// renewing an MFA session profile renews it from its IAM user
+	seen := map[string]bool{name: true}
+	for source := p.OtherProps[awsconfig.MFASourceKey]; source != ""; source = p.OtherProps[awsconfig.MFASourceKey] {
+		if seen[source] {
+			return time.Time{}, fmt.Errorf("profile %s: %s %s leads back to profile %s", name, awsconfig.MFASourceKey, source, source)
+		}
+		seen[source] = true
+		if p, err = store.Profile(source); err != nil {
+			return time.Time{}, err
+		}
+		name = source
+	}
+	if p.Source.Kind != awsconfig.SourceStatic {
+		return time.Time{}, fmt.Errorf("profile %s does not have long-term keys (%s)", name, p.Source)
+	}
+	if serial == "" {
+		serial = p.OtherProps["mfa_serial"]
+	}
+	if serial == "" {
+		return time.Time{}, fmt.Errorf("profile %s sets no mfa_serial, pass --serial", name)
+	}
+	if code == "" {
+		code, err = cli.ReadCode("MFA code for " + serial)
+		if errors.Is(err, cli.ErrNoTerminal) {
+			return time.Time{}, fmt.Errorf("profile %s needs an MFA code, pass --code", name)
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	creds, err := profileCredentials(p)
+	if err != nil {
+		return time.Time{}, err
+	}
+	session, err := stsClient(creds).GetSessionToken(sts.GetSessionTokenInput{
+		SerialNumber: serial,
+		TokenCode:    code,
+		Duration:     duration,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("getting an MFA session for profile %s: %w", name, err)
+	}
+
+	changes, err := replaceProfileCredentials(awsconfig.ProfileUpdate{
+		Profile:      name + mfaSuffix,
+		AccessKey:    session.AccessKeyID,
+		SecretKey:    session.SecretAccessKey,
+		SessionToken: session.SessionToken,
+		Expiration:   session.Expiration,
+		Region:       p.Region,
+		Settings:     map[string]string{awsconfig.MFASourceKey: name},
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("updating AWS credentials: %w", err)
+	}
+	if err := printChanges(changes, "MFA session already up to date"); err != nil {
+		return time.Time{}, err
+	}
+	if cli.Human() {
+		fmt.Printf("Profile %s%s %s\n", name, mfaSuffix, awsconfig.DescribeExpiry(session.Expiration))
+	}
+	return session.Expiration, nil
+}
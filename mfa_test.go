@@ -0,0 +1,106 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useAWSDir points the tool at a temporary pair of AWS files
+func useAWSDir(t *testing.T, configFile, credentials string) awsconfig.Store {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config"), []byte(configFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "credentials"), []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := *awsDir
+	*awsDir = dir
+	t.Cleanup(func() { *awsDir = old })
+	store, _ := awsconfig.Locate(dir)
+	return store
+}
+
+// useSTS points STS and IAM calls at handler
+func useSTS(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	old := config.Current
+	config.Current.STSEndpoint = server.URL
+	config.Current.IAMEndpoint = server.URL
+	t.Cleanup(func() {
+		config.Current = old
+		server.Close()
+	})
+}
+
+func TestMFASession(t *testing.T) {
+	store := useAWSDir(t, "[profile dev]\nregion = eu-west-1\nmfa_serial = arn:aws:iam::111111111111:mfa/alice\n",
+		"[dev]\naws_access_key_id = AKIADEV\naws_secret_access_key = secret\n")
+	expiration := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	calls := 0
+	useSTS(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		r.ParseForm()
+		if r.Form.Get("Action") != "GetSessionToken" || r.Form.Get("SerialNumber") != "arn:aws:iam::111111111111:mfa/alice" ||
+			r.Form.Get("TokenCode") != "123456" || r.Form.Get("DurationSeconds") != "3600" ||
+			!strings.Contains(r.Header.Get("Authorization"), "Credential=AKIADEV/") {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `<ErrorResponse><Error><Code>InvalidParameterValue</Code><Message>unexpected request</Message></Error></ErrorResponse>`)
+			return
+		}
+		io.WriteString(w, `<GetSessionTokenResponse><GetSessionTokenResult><Credentials>
+<AccessKeyId>ASIAMFA</AccessKeyId><SecretAccessKey>mfa-secret</SecretAccessKey>
+<SessionToken>mfa-token</SessionToken><Expiration>`+expiration.Format(time.RFC3339)+`</Expiration>
+</Credentials></GetSessionTokenResult></GetSessionTokenResponse>`)
+	})
+
+	got, err := mfaSession("dev", "", "123456", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(expiration) {
+		t.Errorf("expiration = %v, want %v", got, expiration)
+	}
+	p, err := store.Profile("dev" + mfaSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AccessKey != "ASIAMFA" || p.SessionToken != "mfa-token" || !p.Expiration.Equal(expiration) ||
+		p.Region != "eu-west-1" || p.OtherProps[awsconfig.MFASourceKey] != "dev" {
+		t.Errorf("unexpected session profile %+v", p)
+	}
+
+	// renewing the session profile goes back to its IAM user
+	if _, err := mfaSession("dev"+mfaSuffix, "", "123456", time.Hour); err != nil || calls != 2 {
+		t.Errorf("renewal: %v after %d calls", err, calls)
+	}
+
+	if _, err := mfaSession("dev", "", "000000", time.Hour); err == nil {
+		t.Error("expected the STS error to be returned")
+	}
+}
+
+func TestMFASessionSourceLoops(t *testing.T) {
+	useAWSDir(t, "[profile self]\n"+awsconfig.MFASourceKey+" = self\n\n"+
+		"[profile a]\n"+awsconfig.MFASourceKey+" = b\n\n[profile b]\n"+awsconfig.MFASourceKey+" = a\n", "")
+	for _, name := range []string{"self", "a"} {
+		if _, err := mfaSession(name, "", "123456", time.Hour); err == nil || !strings.Contains(err.Error(), "leads back") {
+			t.Errorf("%s: expected the loop to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestMFASessionWatchNeedsRoomToRenew(t *testing.T) {
+	err := mfaSessionCommand([]string{"--profile", "dev", "--watch", "--duration", "15m", "--refresh-before", "15m"})
+	if err == nil || !strings.Contains(err.Error(), "--refresh-before") {
+		t.Errorf("expected --watch with duration <= refresh-before to be rejected, got %v", err)
+	}
+}
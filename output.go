@@ -0,0 +1,63 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/cli"
+	"aws-multitool/core"
+	"flag"
+	"fmt"
+)
+
+var output = flag.String("output", "table", "how commands print: table, text, json or yaml")
+
+// profileView is a profile in the shape --output prints
+func profileView(p AWSMaster) cli.Profile {
+	view := cli.Profile{
+		Name:      p.Profile,
+		Region:    p.Region,
+		Source:    p.Source.String(),
+		AccountID: p.AccountID,
+		State:     awsconfig.StateOf(p.Expiration).String(),
+		Expires:   p.Expiry(),
+	}
+	if !p.Expiration.IsZero() {
+		expiration := p.Expiration
+		view.Expiration = &expiration
+	}
+	for _, c := range p.Conflicts {
+		view.Conflicts = append(view.Conflicts, c.Key)
+	}
+	return view
+}
+
+// credentialsView is a set of credentials in the shape --output prints
+func credentialsView(creds core.LocalCreds) cli.Credentials {
+	view := cli.Credentials{
+		Profile:         creds.Profile,
+		AccessKeyID:     creds.KeyID,
+		SecretAccessKey: creds.AccessKey,
+		SessionToken:    creds.SessionToken,
+		Region:          creds.Region,
+	}
+	if !creds.Expiration.IsZero() {
+		expiration := creds.Expiration
+		view.Expiration = &expiration
+	}
+	return view
+}
+
+// printChanges renders the edits made to the AWS files, or message when
+// there were none
+func printChanges(changes []awsconfig.Change, message string) error {
+	if len(changes) == 0 && cli.Human() {
+		if message != "" {
+			fmt.Println(message)
+		}
+		return nil
+	}
+	views := []cli.Change{}
+	for _, c := range changes {
+		views = append(views, cli.Change(c))
+	}
+	return cli.Render(views)
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/cli"
+	"aws-multitool/config"
+	"flag"
+	"fmt"
+)
+
+// profileCommand runs `profile use|list|add|copy|rename|delete|pin|unpin`. Any
+// value missing from the flags is asked for with a prompt, so the same
+// code serves the menu and scripts.
+func profileCommand(args []string) error {
+	action := ""
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	} else {
+		action = cli.PromptSelect("What do you want to do?", []string{"add", "copy", "rename", "delete", "pin", "unpin"})
+	}
+
+	switch action {
+	case "use":
+		if len(args) == 0 {
+			_, err := profile()
+			return err
+		}
+		_, err := useProfile(args[0])
+		return err
+	case "list":
+		return profileList(args)
+	case "pin", "unpin":
+		return profilePin(action == "pin", args)
+	}
+
+	store, err := awsStore()
+	if err != nil {
+		return err
+	}
+
+	var changes []awsconfig.Change
+	switch action {
+	case "add":
+		changes, err = profileAdd(store, args)
+	case "copy", "rename":
+		changes, err = profileMove(store, action, args)
+	case "delete":
+		changes, err = profileDelete(store, args)
+	default:
+		return fmt.Errorf("unknown profile action %q, expected use, list, add, copy, rename, delete, pin or unpin", action)
+	}
+	if err != nil {
+		return err
+	}
+	return printChanges(changes, "")
+}
+
+func profileAdd(store awsconfig.Store, args []string) ([]awsconfig.Change, error) {
+	fs := flag.NewFlagSet("profile add", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the new profile")
+	accessKey := fs.String("access-key", "", "aws_access_key_id")
+	secretKey := fs.String("secret-key", "", "aws_secret_access_key")
+	region := fs.String("region", "", "default region")
+	output := fs.String("output", "", "default output format")
+	roleArn := fs.String("role-arn", "", "role to assume instead of static keys")
+	sourceProfile := fs.String("source-profile", "", "profile whose credentials assume the role")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// prompt for anything not given on the command line
+	if fs.NFlag() == 0 {
+		*name = cli.PromptGetInput(cli.PromptContent{Label: "Profile name"})
+		if cli.PromptSelect("Credentials", []string{"access keys", "assume role"}) == "access keys" {
+			*accessKey = cli.PromptGetInput(cli.PromptContent{Label: "AWS Access Key ID"})
+			*secretKey = cli.PromptSecret("AWS Secret Access Key")
+		} else {
+			*roleArn = cli.PromptGetInput(cli.PromptContent{Label: "Role ARN"})
+			*sourceProfile = cli.PromptGetInput(cli.PromptContent{Label: "Source profile"})
+		}
+		*region = cli.PromptOptional("Region", "us-east-1")
+		*output = cli.PromptOptional("Output", "json")
+	}
+	if *name == "" {
+		return nil, fmt.Errorf("profile add needs --name")
+	}
+	if (*accessKey == "") != (*secretKey == "") {
+		return nil, fmt.Errorf("--access-key and --secret-key must be given together")
+	}
+
+	return store.AddProfile(awsconfig.ProfileUpdate{
+		Profile:   *name,
+		AccessKey: *accessKey,
+		SecretKey: *secretKey,
+		Region:    *region,
+		Output:    *output,
+	}, map[string]string{
+		"role_arn":       *roleArn,
+		"source_profile": *sourceProfile,
+	})
+}
+
+func profileMove(store awsconfig.Store, action string, args []string) ([]awsconfig.Change, error) {
+	fs := flag.NewFlagSet("profile "+action, flag.ContinueOnError)
+	from := fs.String("from", "", "existing profile")
+	to := fs.String("to", "", "new profile name")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *from == "" {
+		var err error
+		if *from, err = pickProfileName("Profile to " + action); err != nil {
+			return nil, err
+		}
+	}
+	if *to == "" {
+		*to = cli.PromptGetInput(cli.PromptContent{Label: "New profile name"})
+	}
+
+	if action == "copy" {
+		return store.CopyProfile(*from, *to)
+	}
+	changes, err := store.RenameProfile(*from, *to)
+	if err != nil {
+		return nil, err
+	}
+	return changes, moveProfileRefs(*from, *to)
+}
+
+func profileDelete(store awsconfig.Store, args []string) ([]awsconfig.Change, error) {
+	fs := flag.NewFlagSet("profile delete", flag.ContinueOnError)
+	name := fs.String("name", "", "profile to delete")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *name == "" {
+		var err error
+		if *name, err = pickProfileName("Profile to delete"); err != nil {
+			return nil, err
+		}
+	}
+	if !*yes && !cli.PromptConfirm("Delete profile "+*name+" from both AWS files") {
+		return nil, fmt.Errorf("not deleting %s", *name)
+	}
+	changes, err := store.DeleteProfile(*name)
+	if err != nil {
+		return nil, err
+	}
+	return changes, moveProfileRefs(*name, "")
+}
+
+// moveProfileRefs points the accounts, favorites and recent profiles bound
+// to a renamed profile at its new name, or drops them when to is empty
+// because the profile was deleted
+func moveProfileRefs(from, to string) error {
+	state, err := config.LoadState()
+	if err != nil {
+		return err
+	}
+	if state.MoveProfile(from, to) {
+		if err := state.Save(); err != nil {
+			return err
+		}
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	file, err := config.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	moved := file.MoveProfile(from, to)
+	if len(moved) == 0 {
+		return nil
+	}
+	if err := config.WriteFile(path, file); err != nil {
+		return err
+	}
+	config.Current.MoveProfile(from, to)
+	if cli.Human() {
+		for _, a := range moved {
+			if to == "" {
+				fmt.Printf("Removed account %s, its profile %s is gone\n", a.Name, from)
+			} else {
+				fmt.Printf("Account %s now writes to profile %s\n", a.Name, to)
+			}
+		}
+	}
+	return nil
+}
+
+// profilePin adds a profile to the favorites the picker lists first, or
+// takes it off
+func profilePin(pin bool, args []string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	} else {
+		var err error
+		if name, err = pickProfileName("Which profile?"); err != nil {
+			return err
+		}
+	}
+
+	// unpinning a profile that was deleted meanwhile is still allowed
+	if pin {
+		store, err := awsStore()
+		if err != nil {
+			return err
+		}
+		if _, err := store.Profile(name); err != nil {
+			return err
+		}
+	}
+
+	state, err := config.LoadState()
+	if err != nil {
+		return err
+	}
+	state.SetFavorite(name, pin)
+	return state.Save()
+}
+
+// rememberProfile moves a profile to the top of the picker's recently
+// used list
+func rememberProfile(name string) {
+	state, err := config.LoadState()
+	if err == nil {
+		state.Use(name)
+		err = state.Save()
+	}
+	if err != nil {
+		cli.Error("saving recent profiles: " + err.Error())
+	}
+}
+
+// profileList prints every profile with its credential source and expiry,
+// or just the names for shell completion
+func profileList(args []string) error {
+	fs := flag.NewFlagSet("profile list", flag.ContinueOnError)
+	namesOnly := fs.Bool("names", false, "print only the profile names")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	profiles, err := readAWSMasterFile()
+	if err != nil {
+		return err
+	}
+	views := []cli.Profile{}
+	for _, p := range profiles {
+		if *namesOnly {
+			fmt.Println(p.Profile)
+			continue
+		}
+		views = append(views, profileView(p))
+	}
+	if *namesOnly {
+		return nil
+	}
+	return cli.Render(views)
+}
+
+// pickProfileName lets the user choose one of the existing profiles
+func pickProfileName(label string) (string, error) {
+	profiles, err := readAWSMasterFile()
+	if err != nil {
+		return "", err
+	}
+	if len(profiles) == 0 {
+		return "", fmt.Errorf("no profiles found")
+	}
+	var names []string
+	for _, p := range profiles {
+		names = append(names, p.Profile)
+	}
+	return cli.PromptSelect(label, names), nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"aws-multitool/config"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProfileRenameAndDeleteMoveRefs(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv(config.PathEnv, path)
+	defer func(c config.Config) { config.Current = c }(config.Current)
+	useAWSDir(t, "[profile sandbox-alice]\nregion = eu-west-1\n\n[profile dev]\nregion = eu-west-1\n", "")
+
+	file := config.Config{Accounts: []config.Account{{Name: "alice", Provider: "acloudguru", Profile: "sandbox-alice"}}}
+	if err := config.WriteFile(path, file); err != nil {
+		t.Fatal(err)
+	}
+	config.Current.Accounts = file.Accounts
+	state := config.State{Favorites: []string{"sandbox-alice"}, Recent: []string{"dev", "sandbox-alice"}}
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := profileCommand([]string{"rename", "--from", "sandbox-alice", "--to", "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := profileCommand([]string{"delete", "--name", "dev", "--yes"}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := config.LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state.Favorites, []string{"alice"}) || !reflect.DeepEqual(state.Recent, []string{"alice"}) {
+		t.Errorf("state still names old profiles: %+v", state)
+	}
+	file, err = config.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := file.Account("alice"); !ok || a.Profile != "alice" {
+		t.Errorf("account alice is bound to %+v in the file", a)
+	}
+	if a, _ := config.Current.Account("alice"); a.Profile != "alice" {
+		t.Errorf("account alice is bound to %q in memory", a.Profile)
+	}
+}
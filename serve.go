@@ -0,0 +1,84 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/core"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// serveCommand runs a local ECS container credentials endpoint for a
+// profile or the live sandbox. The AWS files (or the sandbox cache) are
+// read again on every request, so clients pick up refreshed keys without
+// restarting. Containers need --network host, the SDKs only accept plain
+// http on loopback addresses.
+func serveCommand(args []string) error {
+	fs := flag.NewFlagSet("serve-credentials", flag.ContinueOnError)
+	name := fs.String("profile", "", "profile to serve")
+	fromSandbox := fs.Bool("sandbox", false, "serve the sandbox, starting a new one when it expires")
+	provider := fs.String("provider", "", "sandbox provider, defaults to the profile's "+awsconfig.ProviderKey)
+	addr := fs.String("addr", "127.0.0.1:0", "address to listen on")
+	token := fs.String("token", "", "authorization token clients must send, random by default")
+	before := fs.Duration("refresh-before", 5*time.Minute, "start a new sandbox when the current one expires within this long")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var fetch func() (core.LocalCreds, error)
+	if *fromSandbox {
+		if *name == "" {
+			*name = "sandbox"
+		}
+		fetch = func() (core.LocalCreds, error) {
+			return currentSandboxCreds(os.Stderr, *name, *provider, *before)
+		}
+	} else {
+		if *name == "" {
+			var err error
+			if *name, err = pickProfileName("Profile to serve"); err != nil {
+				return err
+			}
+		}
+		store, err := awsStore()
+		if err != nil {
+			return err
+		}
+		fetch = func() (core.LocalCreds, error) {
+			p, err := store.Profile(*name)
+			if err != nil {
+				return core.LocalCreds{}, err
+			}
+			return profileCredentials(p)
+		}
+		// fail now rather than on the first request, and ask for an MFA
+		// code while there is someone to answer: requests reuse the
+		// cached role session and cannot prompt
+		if _, err := fetch(); err != nil {
+			return err
+		}
+		promptMFA = false
+	}
+
+	if *token == "" {
+		var err error
+		if *token, err = core.NewToken(); err != nil {
+			return err
+		}
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("export %s=http://%s/credentials\n", core.ContainerURIEnv, listener.Addr())
+	fmt.Printf("export %s=%s\n", core.ContainerTokenEnv, *token)
+	fmt.Fprintln(os.Stderr, "Serving credentials of", *name, "until interrupted")
+
+	mux := http.NewServeMux()
+	mux.Handle("/credentials", core.CredentialsHandler(*token, fetch))
+	return http.Serve(listener, mux)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"aws-multitool/cli"
+	"aws-multitool/config"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// flagSettings maps global flags to the settings they override
+var flagSettings = map[string]string{
+	"aws-dir": "aws_dir",
+	"output":  "output",
+}
+
+// loadConfig fills config.Current from the defaults, the config file, the
+// environment and finally the flags given on the command line. A broken
+// config file is reported but not fatal, so `config edit` can repair it.
+func loadConfig() {
+	settings, sources, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: ignoring config file:", err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if key, ok := flagSettings[f.Name]; ok {
+			settings.Set(key, f.Value.String())
+			sources[key] = config.FromFlag
+		}
+	})
+	*output = settings.Output
+	config.Current, config.Sources = settings, sources
+}
+
+// configCommand shows and changes the tool's settings:
+// config get [key] | set key value | edit | path | migrate-db [old]
+func configCommand(args []string) error {
+	action, args, err := subcommand(args, "get", "set", "edit", "path", "migrate-db")
+	if err != nil {
+		return err
+	}
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "path":
+		fmt.Println(path)
+		return nil
+	case "migrate-db":
+		return migrateDB(args)
+	case "get":
+		if len(args) == 0 {
+			var settings []cli.Setting
+			for _, key := range config.Keys() {
+				value, _ := config.Current.Get(key)
+				settings = append(settings, cli.Setting{Key: key, Value: value, Source: config.Sources[key]})
+			}
+			return cli.Render(settings)
+		}
+		value, err := config.Current.Get(args[0])
+		if err != nil {
+			return err
+		}
+		if cli.Human() {
+			fmt.Println(value)
+			return nil
+		}
+		return cli.Render(cli.Setting{Key: args[0], Value: value, Source: config.Sources[args[0]]})
+	case "set":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: config set key value")
+		}
+		switch args[0] {
+		case "output":
+			if err := cli.CheckOutputFormat(args[1]); err != nil {
+				return err
+			}
+		case "ui":
+			if err := config.CheckUI(args[1]); err != nil {
+				return err
+			}
+		}
+		file, err := config.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := file.Set(args[0], args[1]); err != nil {
+			return err
+		}
+		if err := config.WriteFile(path, file); err != nil {
+			return err
+		}
+		if cli.Human() {
+			fmt.Println("Set", args[0], "in", path)
+		}
+		return nil
+	}
+	return editConfig(path)
+}
+
+// migrateDB moves a database from before the data dir, ./credentials.db
+// unless another path is given, to the configured db
+func migrateDB(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: config migrate-db [old]")
+	}
+	from := config.LegacyDB
+	if len(args) == 1 {
+		from = args[0]
+	}
+	if err := config.MigrateDB(from, config.Current.DB); err != nil {
+		return fmt.Errorf("moving %s: %w", from, err)
+	}
+	if cli.Human() {
+		fmt.Println("Moved", from, "to", config.Current.DB)
+	}
+	return nil
+}
+
+// editConfig opens the config file in $VISUAL or $EDITOR and checks that
+// it still parses afterwards
+func editConfig(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := config.WriteFile(path, config.Config{}); err != nil {
+			return err
+		}
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	cmd := exec.Command(editor, path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running %s: %w", editor, err)
+	}
+	_, err := config.ReadFile(path)
+	return err
+}
@@ -0,0 +1,73 @@
+package sts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// FederationEndpoint is where temporary credentials are exchanged for a
+// console sign-in token
+const FederationEndpoint = "https://signin.aws.amazon.com/federation"
+
+// Issuer names the tool on the AWS sign-in page
+const Issuer = "aws-multitool"
+
+// SigninURL exchanges temporary credentials for a sign-in token at
+// endpoint, FederationEndpoint if empty, and returns a URL that opens
+// destination already signed in. The URL is valid for 15 minutes.
+func SigninURL(endpoint string, creds Credentials, destination string) (string, error) {
+	if endpoint == "" {
+		endpoint = FederationEndpoint
+	}
+	if creds.SessionToken == "" {
+		return "", errors.New("console sign-in needs temporary credentials")
+	}
+	session, err := json.Marshal(map[string]string{
+		"sessionId":    creds.AccessKeyID,
+		"sessionKey":   creds.SecretAccessKey,
+		"sessionToken": creds.SessionToken,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{"Action": {"getSigninToken"}, "Session": {string(session)}}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(endpoint + "?" + query.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("federation endpoint: HTTP %d", resp.StatusCode)
+	}
+	var token struct {
+		SigninToken string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("federation endpoint: %w", err)
+	}
+	if token.SigninToken == "" {
+		return "", errors.New("federation endpoint returned no sign-in token")
+	}
+
+	login := url.Values{
+		"Action":      {"login"},
+		"Issuer":      {Issuer},
+		"Destination": {destination},
+		"SigninToken": {token.SigninToken},
+	}
+	return endpoint + "?" + login.Encode(), nil
+}
+
+// ConsoleDestination is the console home page, in region if one is given
+func ConsoleDestination(region string) string {
+	if region == "" {
+		return "https://console.aws.amazon.com/"
+	}
+	return "https://" + region + ".console.aws.amazon.com/console/home?region=" + region
+}
@@ -0,0 +1,222 @@
+package sts
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRegion is used when a profile sets no region
+const DefaultRegion = "us-east-1"
+
+const apiVersion = "2011-06-15"
+
+// IAMEndpoint is the global IAM endpoint
+const IAMEndpoint = "https://iam.amazonaws.com"
+
+// Client calls the STS query API with one set of credentials
+type Client struct {
+	Credentials Credentials
+	Region      string
+	// Endpoint overrides the regional https://sts.<region>.amazonaws.com
+	Endpoint string
+	// IAMEndpoint overrides IAMEndpoint, for AccountAlias
+	IAMEndpoint string
+	HTTPClient  *http.Client
+}
+
+// New returns a client for region, DefaultRegion if it is empty
+func New(creds Credentials, region string) *Client {
+	if region == "" {
+		region = DefaultRegion
+	}
+	return &Client{
+		Credentials: creds,
+		Region:      region,
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// CallerIdentity is the answer of GetCallerIdentity
+type CallerIdentity struct {
+	Account string `xml:"Account"`
+	Arn     string `xml:"Arn"`
+	UserID  string `xml:"UserId"`
+}
+
+// AssumeRoleInput are the parameters of AssumeRole. SerialNumber and
+// TokenCode are only needed when the role requires MFA.
+type AssumeRoleInput struct {
+	RoleArn         string
+	RoleSessionName string
+	ExternalID      string
+	SerialNumber    string
+	TokenCode       string
+	Duration        time.Duration
+}
+
+// GetSessionTokenInput are the parameters of GetSessionToken
+type GetSessionTokenInput struct {
+	SerialNumber string
+	TokenCode    string
+	Duration     time.Duration
+}
+
+// Error is an error response from STS or IAM
+type Error struct {
+	Service    string
+	StatusCode int
+	Code       string `xml:"Error>Code"`
+	Message    string `xml:"Error>Message"`
+	RequestID  string `xml:"RequestId"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%s: HTTP %d", e.Service, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Service, e.Code, e.Message)
+}
+
+// GetCallerIdentity returns the account and ARN the credentials belong to
+func (c *Client) GetCallerIdentity() (CallerIdentity, error) {
+	var out struct {
+		Result CallerIdentity `xml:"GetCallerIdentityResult"`
+	}
+	err := c.call("GetCallerIdentity", url.Values{}, &out)
+	return out.Result, err
+}
+
+// AssumeRole returns temporary credentials for a role
+func (c *Client) AssumeRole(in AssumeRoleInput) (Credentials, error) {
+	name := in.RoleSessionName
+	if name == "" {
+		name = "aws-multitool-" + strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	params := url.Values{"RoleArn": {in.RoleArn}, "RoleSessionName": {name}}
+	if in.ExternalID != "" {
+		params.Set("ExternalId", in.ExternalID)
+	}
+	setMFA(params, in.SerialNumber, in.TokenCode, in.Duration)
+
+	var out struct {
+		Credentials Credentials `xml:"AssumeRoleResult>Credentials"`
+	}
+	err := c.call("AssumeRole", params, &out)
+	return out.Credentials, err
+}
+
+// GetSessionToken returns temporary credentials for the client's own
+// long-term credentials, MFA-authenticated when a token code is given
+func (c *Client) GetSessionToken(in GetSessionTokenInput) (Credentials, error) {
+	params := url.Values{}
+	setMFA(params, in.SerialNumber, in.TokenCode, in.Duration)
+
+	var out struct {
+		Credentials Credentials `xml:"GetSessionTokenResult>Credentials"`
+	}
+	err := c.call("GetSessionToken", params, &out)
+	return out.Credentials, err
+}
+
+// GetFederationToken returns temporary credentials for a federated user
+// called name, allowed what both policy and the client's own credentials
+// allow
+func (c *Client) GetFederationToken(name, policy string, duration time.Duration) (Credentials, error) {
+	params := url.Values{"Name": {name}}
+	if policy != "" {
+		params.Set("Policy", policy)
+	}
+	if duration > 0 {
+		params.Set("DurationSeconds", strconv.Itoa(int(duration.Seconds())))
+	}
+
+	var out struct {
+		Credentials Credentials `xml:"GetFederationTokenResult>Credentials"`
+	}
+	err := c.call("GetFederationToken", params, &out)
+	return out.Credentials, err
+}
+
+func setMFA(params url.Values, serial, code string, duration time.Duration) {
+	if serial != "" {
+		params.Set("SerialNumber", serial)
+		params.Set("TokenCode", code)
+	}
+	if duration > 0 {
+		params.Set("DurationSeconds", strconv.Itoa(int(duration.Seconds())))
+	}
+}
+
+// AccountAlias returns the IAM alias of the credentials' account, ""
+// if it has none
+func (c *Client) AccountAlias() (string, error) {
+	endpoint := c.IAMEndpoint
+	if endpoint == "" {
+		endpoint = IAMEndpoint
+	}
+	var out struct {
+		Aliases []string `xml:"ListAccountAliasesResult>AccountAliases>member"`
+	}
+	// IAM is global and signed for us-east-1
+	err := c.post(endpoint, "iam", DefaultRegion, "2010-05-08", "ListAccountAliases", url.Values{}, &out)
+	if err != nil || len(out.Aliases) == 0 {
+		return "", err
+	}
+	return out.Aliases[0], nil
+}
+
+func (c *Client) endpoint() string {
+	if c.Endpoint != "" {
+		return c.Endpoint
+	}
+	return "https://sts." + c.Region + ".amazonaws.com"
+}
+
+// call posts a signed STS request and decodes the XML answer
+func (c *Client) call(action string, params url.Values, out interface{}) error {
+	return c.post(c.endpoint(), "sts", c.Region, apiVersion, action, params, out)
+}
+
+// post sends a signed query API request and decodes the XML answer
+func (c *Client) post(endpoint, service, region, version, action string, params url.Values, out interface{}) error {
+	params.Set("Action", action)
+	params.Set("Version", version)
+	body := []byte(params.Encode())
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	Sign(req, body, c.Credentials, region, service, time.Now())
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		e := &Error{Service: service, StatusCode: resp.StatusCode}
+		xml.Unmarshal(data, e)
+		return e
+	}
+	if err := xml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s: decoding %s response: %w", service, action, err)
+	}
+	return nil
+}
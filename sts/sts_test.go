@@ -0,0 +1,116 @@
+package sts
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSign checks the get-vanilla case of the AWS SigV4 test suite
+func TestSign(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	Sign(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s\nwant %s", got, want)
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKIAEXAMPLE/") ||
+			r.Header.Get("X-Amz-Security-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `<ErrorResponse><Error><Code>InvalidClientTokenId</Code><Message>bad signature</Message></Error></ErrorResponse>`)
+			return
+		}
+		r.ParseForm()
+		switch r.Form.Get("Action") {
+		case "GetCallerIdentity":
+			io.WriteString(w, `<GetCallerIdentityResponse><GetCallerIdentityResult>
+<Arn>arn:aws:iam::123456789012:user/alice</Arn><UserId>AIDAEXAMPLE</UserId><Account>123456789012</Account>
+</GetCallerIdentityResult></GetCallerIdentityResponse>`)
+		case "ListAccountAliases":
+			io.WriteString(w, `<ListAccountAliasesResponse><ListAccountAliasesResult>
+<AccountAliases><member>acme-prod</member></AccountAliases>
+</ListAccountAliasesResult></ListAccountAliasesResponse>`)
+		case "AssumeRole":
+			if r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/admin" || r.Form.Get("ExternalId") != "ext" || r.Form.Get("DurationSeconds") != "900" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			io.WriteString(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials>
+<AccessKeyId>ASIAEXAMPLE</AccessKeyId><SecretAccessKey>secret</SecretAccessKey>
+<SessionToken>session</SessionToken><Expiration>2026-10-18T12:00:00Z</Expiration>
+</Credentials></AssumeRoleResult></AssumeRoleResponse>`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `<ErrorResponse><Error><Code>InvalidAction</Code><Message>nope</Message></Error></ErrorResponse>`)
+		}
+	}))
+	defer server.Close()
+
+	c := New(Credentials{AccessKeyID: "AKIAEXAMPLE", SecretAccessKey: "secret", SessionToken: "token"}, "")
+	c.Endpoint = server.URL
+
+	identity, err := c.GetCallerIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity != (CallerIdentity{"123456789012", "arn:aws:iam::123456789012:user/alice", "AIDAEXAMPLE"}) {
+		t.Errorf("identity = %+v", identity)
+	}
+
+	c.IAMEndpoint = server.URL
+	if alias, err := c.AccountAlias(); err != nil || alias != "acme-prod" {
+		t.Errorf("AccountAlias() = %q, %v", alias, err)
+	}
+
+	creds, err := c.AssumeRole(AssumeRoleInput{RoleArn: "arn:aws:iam::123456789012:role/admin", ExternalID: "ext", Duration: 15 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessKeyID != "ASIAEXAMPLE" || !creds.Expiration.Equal(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("credentials = %+v", creds)
+	}
+
+	_, err = c.GetSessionToken(GetSessionTokenInput{})
+	if e, ok := err.(*Error); !ok || e.Code != "InvalidAction" || e.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected an InvalidAction error, got %v", err)
+	}
+}
+
+func TestSigninURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var session map[string]string
+		if r.URL.Query().Get("Action") != "getSigninToken" || json.Unmarshal([]byte(r.URL.Query().Get("Session")), &session) != nil || session["sessionToken"] != "session" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, `{"SigninToken":"t0ken"}`)
+	}))
+	defer server.Close()
+
+	creds := Credentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secret", SessionToken: "session"}
+	got, err := SigninURL(server.URL, creds, ConsoleDestination("eu-west-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(got)
+	q := u.Query()
+	if q.Get("Action") != "login" || q.Get("SigninToken") != "t0ken" || q.Get("Destination") != "https://eu-west-1.console.aws.amazon.com/console/home?region=eu-west-1" {
+		t.Errorf("unexpected sign-in URL %s", got)
+	}
+
+	if _, err := SigninURL(server.URL, Credentials{AccessKeyID: "AKIAEXAMPLE", SecretAccessKey: "secret"}, ""); err == nil {
+		t.Error("expected an error for long-term credentials")
+	}
+}
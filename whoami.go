@@ -0,0 +1,116 @@
+package main
+
+import (
+	"aws-multitool/awsconfig"
+	"aws-multitool/cli"
+	"aws-multitool/sts"
+	"errors"
+	"net"
+	"sync"
+)
+
+// whoamiAll asks STS who every profile is, parallel profiles at a time,
+// prints one row per profile in file order and records the account IDs
+// it learned in the config file
+func whoamiAll(parallel int) error {
+	profiles, err := readAWSMasterFile()
+	if err != nil {
+		return err
+	}
+	// nobody can answer prompts from several workers at once
+	promptMFA = false
+
+	results := identifyAll(profiles, parallel)
+	fillAccountIDs(profiles, results)
+	return cli.Render(results)
+}
+
+// identifyAll runs identify over profiles with at most parallel calls in
+// flight, keeping the results in profile order
+func identifyAll(profiles []AWSMaster, parallel int) []cli.ProfileIdentity {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]cli.ProfileIdentity, len(profiles))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = identify(profiles[i])
+			}
+		}()
+	}
+	for i := range profiles {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// fillAccountIDs sets the AccountID of the profiles that record no
+// account with the one their credentials answered for. The AWS files are
+// left alone.
+func fillAccountIDs(profiles []AWSMaster, results []cli.ProfileIdentity) {
+	for i := range profiles {
+		if profiles[i].AccountID == "" && results[i].Error == "" {
+			profiles[i].AccountID = results[i].Account
+		}
+	}
+}
+
+// identify looks up who p authenticates as
+func identify(p AWSMaster) cli.ProfileIdentity {
+	view := cli.ProfileIdentity{Profile: p.Profile, Account: p.AccountID, Source: p.Source.String()}
+	if p.Source.Kind == awsconfig.SourceNone {
+		view.Error = "no credentials"
+		return view
+	}
+	if awsconfig.StateOf(p.Expiration) == awsconfig.Expired {
+		view.Error = "expired"
+		return view
+	}
+
+	creds, err := profileCredentials(p)
+	if err != nil {
+		view.Error = identityError(err)
+		return view
+	}
+	client := stsClient(creds)
+	identity, err := client.GetCallerIdentity()
+	if err != nil {
+		view.Error = identityError(err)
+		return view
+	}
+	view.Account = identity.Account
+	view.Arn = identity.Arn
+	// most principals may not list aliases; that is not worth reporting
+	view.Alias, _ = client.AccountAlias()
+	return view
+}
+
+// identityError shortens the common STS failures for the table
+func identityError(err error) string {
+	var e *sts.Error
+	if errors.As(err, &e) {
+		switch e.Code {
+		case "ExpiredToken", "RequestExpired":
+			return "expired"
+		case "SignatureDoesNotMatch", "IncompleteSignature":
+			return "invalid signature"
+		case "InvalidClientTokenId":
+			return "invalid access key"
+		case "AccessDenied":
+			return "access denied"
+		}
+		return e.Error()
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timed out"
+	}
+	return err.Error()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"aws-multitool/sts"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIdentityError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&sts.Error{Code: "ExpiredToken"}, "expired"},
+		{&sts.Error{Code: "RequestExpired"}, "expired"},
+		{&sts.Error{Code: "SignatureDoesNotMatch"}, "invalid signature"},
+		{&sts.Error{Code: "InvalidClientTokenId"}, "invalid access key"},
+		{fmt.Errorf("assuming a role: %w", &sts.Error{Code: "AccessDenied"}), "access denied"},
+		{fmt.Errorf("Post: %w", timeoutError{}), "timed out"},
+		{errors.New("no route to host"), "no route to host"},
+	}
+	for _, tt := range tests {
+		if got := identityError(tt.err); got != tt.want {
+			t.Errorf("identityError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+var credentialPattern = regexp.MustCompile(`Credential=(\w+)/`)
+
+// identityStub answers GetCallerIdentity after delay(key), with an
+// account ID of eleven 1s and the last character of the access key.
+// Alias lookups are denied.
+func identityStub(delay func(key string) time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m := credentialPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+		if m == nil {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `<ErrorResponse><Error><Code>IncompleteSignature</Code><Message>unsigned</Message></Error></ErrorResponse>`)
+			return
+		}
+		key := m[1]
+		time.Sleep(delay(key))
+		r.ParseForm()
+		if r.Form.Get("Action") != "GetCallerIdentity" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `<ErrorResponse><Error><Code>AccessDenied</Code><Message>no aliases</Message></Error></ErrorResponse>`)
+			return
+		}
+		if key == "AKIADENIED" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `<ErrorResponse><Error><Code>InvalidClientTokenId</Code><Message>bad key</Message></Error></ErrorResponse>`)
+			return
+		}
+		account := "11111111111" + key[len(key)-1:]
+		io.WriteString(w, `<GetCallerIdentityResponse><GetCallerIdentityResult>
+<Arn>arn:aws:iam::`+account+`:user/`+key+`</Arn><UserId>AIDA`+key+`</UserId><Account>`+account+`</Account>
+</GetCallerIdentityResult></GetCallerIdentityResponse>`)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	store := useAWSDir(t, "[profile empty]\nregion = us-east-1\n", `[dev]
+aws_access_key_id = AKIADEV1
+aws_secret_access_key = secret
+
+[denied]
+aws_access_key_id = AKIADENIED
+aws_secret_access_key = secret
+
+[old]
+aws_access_key_id = ASIAOLD
+aws_secret_access_key = secret
+aws_session_token = token
+x_security_token_expires = 2020-01-01T00:00:00Z
+`)
+	useSTS(t, identityStub(func(string) time.Duration { return 0 }))
+
+	want := map[string]string{
+		"dev":    "111111111111 arn:aws:iam::111111111111:user/AKIADEV1 ",
+		"denied": "  invalid access key",
+		"old":    "  expired",
+		"empty":  "  no credentials",
+	}
+	for name, w := range want {
+		p, err := store.Profile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		view := identify(p)
+		if got := view.Account + " " + view.Arn + " " + view.Error; got != w {
+			t.Errorf("identify(%s) = %q, want %q", name, got, w)
+		}
+	}
+}
+
+func TestWhoamiAll(t *testing.T) {
+	useAWSDir(t, "[profile p1]\naws_account_id = 999999999999\n", `[p1]
+aws_access_key_id = AKIAP1
+aws_secret_access_key = s
+
+[p2]
+aws_access_key_id = AKIAP2
+aws_secret_access_key = s
+
+[p3]
+aws_access_key_id = AKIAP3
+aws_secret_access_key = s
+
+[p4]
+aws_access_key_id = AKIAP4
+aws_secret_access_key = s
+
+[p5]
+aws_access_key_id = AKIAP5
+aws_secret_access_key = s
+
+[slow]
+aws_access_key_id = AKIASLOW
+aws_secret_access_key = s
+`)
+	var mu sync.Mutex
+	inFlight, most := 0, 0
+	useSTS(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > most {
+			most = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		identityStub(func(key string) time.Duration {
+			if key == "AKIASLOW" {
+				return 500 * time.Millisecond
+			}
+			return 20 * time.Millisecond
+		})(w, r)
+	})
+	defer func(d time.Duration) { stsTimeout = d }(stsTimeout)
+	stsTimeout = 200 * time.Millisecond
+
+	profiles, err := readAWSMasterFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := identifyAll(profiles, 2)
+	mu.Lock()
+	if most > 2 {
+		t.Errorf("%d calls were in flight at once, the pool allows 2", most)
+	}
+	mu.Unlock()
+	for i, r := range results {
+		if r.Profile != profiles[i].Profile {
+			t.Errorf("result %d is for %s, want %s", i, r.Profile, profiles[i].Profile)
+		}
+		if r.Profile == "slow" {
+			if r.Error != "timed out" {
+				t.Errorf("slow profile: got error %q, want timed out", r.Error)
+			}
+		} else if r.Error != "" || r.Account == "" {
+			t.Errorf("%s: got %+v", r.Profile, r)
+		}
+	}
+
+	before, _ := os.ReadFile(filepath.Join(*awsDir, "config"))
+	fillAccountIDs(profiles, results)
+	for i, want := range []string{"999999999999", "111111111112", "111111111113", "111111111114", "111111111115", ""} {
+		if got := profiles[i].AccountID; got != want {
+			t.Errorf("%s: AccountID = %q, want %q", profiles[i].Profile, got, want)
+		}
+	}
+	if after, _ := os.ReadFile(filepath.Join(*awsDir, "config")); string(after) != string(before) {
+		t.Errorf("whoami changed the config file:\n%s", after)
+	}
+}